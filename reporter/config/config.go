@@ -3,10 +3,64 @@ package config
 
 // Config represents the configuration for a program.
 type Config struct {
-	Input    string
+	Input string
+	// Output is a comma-separated list of outputs in the form of [format:]path.
 	Output   string
 	Root     string
 	Cutlines *Cutlines
+	// Template is the path of a custom HTML template file.
+	Template string
+	// CSS is the path of a CSS file appended to the default HTML styles.
+	CSS string
+	// Meta is the metadata displayed in the header of the HTML report.
+	Meta map[string]string
+	// Palette is the color palette of the HTML report.
+	Palette string
+	// Sidebar enables the tree sidebar navigation of the HTML report.
+	Sidebar bool
+	// Jobs is the number of source files loaded concurrently.
+	Jobs int
+	// Lazy embeds sources in the HTML report compressed, rendering them when opened.
+	Lazy bool
+	// Resolver is the strategy to resolve the directories of packages: auto, mod or golist.
+	Resolver string
+	// Src is the source root packages are resolved from and relative source paths are read from.
+	Src string
+	// SourceMap rewrites the path prefixes of source files, e.g. from the layout of a CI machine.
+	SourceMap []*SourceMapping
+	// Tolerant renders reports even when sources are missing, warning about the skipped files.
+	Tolerant bool
+	// Deps is the way to handle modules replaced by local directories or vendored: include, exclude or group.
+	Deps string
+	// Go is the path of the go command used to resolve packages.
+	Go string
+	// Tags are the comma-separated build tags used to resolve packages.
+	Tags string
+	// GoFlags, GOOS and GOARCH are the environment variables used to resolve packages.
+	GoFlags string
+	GOOS    string
+	GOARCH  string
+	// Depth limits the levels of the tree of the text summary. Every level is shown when not positive.
+	Depth int
+	// Flat lists packages instead of the tree in the text summary.
+	Flat bool
+	// Sort is the order of the rows of summaries: name or coverage.
+	Sort string
+	// Color is whether the text summary is colored: auto, always or never.
+	Color string
+	// Baseline is the path of a previous JSON report the Markdown summary shows deltas against.
+	Baseline string
+	// Worst is the number of the least covered files listed in the Markdown summary.
+	Worst int
+	// BadgeDir is the path of the directory whose coverage badges show. The initial directory is used when empty.
+	BadgeDir string
+	// RepoRoot is the root of the repository paths of annotations are relative to.
+	// The repository containing the source root is used when empty.
+	RepoRoot string
+	// Changed is the path of a unified diff limiting annotations to the changed lines.
+	Changed string
+	// SARIFResults is what the results of the SARIF log report: blocks or functions.
+	SARIFResults string
 }
 
 // Cutlines represents the values for safe, warning and danger.
@@ -14,3 +68,15 @@ type Cutlines struct {
 	Safe    float64
 	Warning float64
 }
+
+// Output represents a single report output.
+type Output struct {
+	Format string
+	Path   string
+}
+
+// SourceMapping represents a rule rewriting the path prefix Old of source files to New.
+type SourceMapping struct {
+	Old string
+	New string
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// badgeLabel is the label of badges.
+const badgeLabel = "coverage"
+
+// badgeColors are the SVG colors and shields.io color names of the coverage class names.
+var badgeColors = map[string][2]string{
+	"safe":    {"#4c1", "brightgreen"},
+	"warning": {"#dfb317", "yellow"},
+	"danger":  {"#e05d44", "red"},
+	"":        {"#9f9f9f", "lightgrey"},
+}
+
+// badgeSVG is the SVG of a flat badge, formatted with the width, the label and message widths, their centers,
+// the color, and the label and message texts.
+const badgeSVG = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[7]s: %[8]s">
+<title>%[7]s: %[8]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[4]d" y="15" fill="#010101" fill-opacity=".3">%[7]s</text><text x="%[4]d" y="14">%[7]s</text>
+<text x="%[5]d" y="15" fill="#010101" fill-opacity=".3">%[8]s</text><text x="%[5]d" y="14">%[8]s</text>
+</g>
+</svg>
+`
+
+// BadgeRenderer renders the coverage of a GoProject as an SVG badge or a shields.io endpoint JSON.
+type BadgeRenderer struct {
+	// Dir is the path of the directory whose coverage is shown. The initial directory is used when empty.
+	Dir string
+	// Shields renders the JSON of a shields.io endpoint instead of SVG.
+	Shields bool
+}
+
+// NewBadgeRenderer returns a new BadgeRenderer rendering SVG for the given configuration.
+func NewBadgeRenderer(cfg *config.Config) (Renderer, error) {
+	return &BadgeRenderer{Dir: cfg.BadgeDir}, nil
+}
+
+// NewShieldsRenderer returns a new BadgeRenderer rendering shields.io endpoint JSON for the given configuration.
+func NewShieldsRenderer(cfg *config.Config) (Renderer, error) {
+	return &BadgeRenderer{Dir: cfg.BadgeDir, Shields: true}, nil
+}
+
+// ShieldsEndpoint is the JSON of a shields.io endpoint; see https://shields.io/badges/endpoint-badge.
+type ShieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// Render writes the badge of the coverage of the directory to the provided io.Writer.
+func (r *BadgeRenderer) Render(gp *GoProject, wr io.Writer) error {
+	dir, err := r.findDir(gp)
+	if err != nil {
+		return err
+	}
+
+	var className string
+	if gp.Cutlines != nil {
+		className = coverageClassName(dir.GoListItem, gp.Cutlines)
+	}
+	color := badgeColors[className]
+	message := fmt.Sprintf("%.1f%%", dir.Percent())
+	if dir.StmtCount == 0 {
+		message = "unknown"
+	}
+
+	if r.Shields {
+		return json.NewEncoder(wr).Encode(&ShieldsEndpoint{SchemaVersion: 1, Label: badgeLabel, Message: message, Color: color[1]})
+	}
+	labelWidth, messageWidth := badgeTextWidth(badgeLabel), badgeTextWidth(message)
+	_, err = fmt.Fprintf(wr, badgeSVG, labelWidth+messageWidth, labelWidth, messageWidth,
+		labelWidth/2, labelWidth+messageWidth/2, color[0], badgeLabel, message)
+	return err
+}
+
+// findDir returns the directory of the badge, looked up by its import path or displayed path.
+func (r *BadgeRenderer) findDir(gp *GoProject) (*GoDir, error) {
+	if r.Dir == "" {
+		return gp.InitialDir(), nil
+	}
+	if dir, ok := gp.Dirs[r.Dir]; ok {
+		return dir, nil
+	}
+	for _, dir := range gp.Dirs {
+		if dir.Path == r.Dir {
+			return dir, nil
+		}
+	}
+	return nil, fmt.Errorf("can't find directory %q for the badge", r.Dir)
+}
+
+// badgeTextWidth returns the approximate width in pixels of the text of a badge with its padding.
+func badgeTextWidth(text string) int {
+	return 7*len(text) + 10
+}
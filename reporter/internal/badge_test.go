@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadgeRender(t *testing.T) {
+	gp := newTextTestProject()
+
+	t.Run("should render svg badge of initial directory", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&BadgeRenderer{}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `aria-label="coverage: 41.7%"`)
+		assert.Contains(t, buf.String(), `fill="#dfb317"`)
+		assert.NoError(t, xml.Unmarshal([]byte(buf.String()), new(struct{})))
+	})
+
+	t.Run("should render badge of chosen directory", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&BadgeRenderer{Dir: "a/b/d"}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `aria-label="coverage: 100.0%"`)
+		assert.Contains(t, buf.String(), `fill="#4c1"`)
+
+		other := newTextTestProject()
+		other.Module = &Module{Path: "a"}
+		dir := other.SafeDir("a/g")
+		buf.Reset()
+		err = (&BadgeRenderer{Dir: "g"}).Render(other, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "g", dir.Path)
+		assert.Contains(t, buf.String(), `aria-label="coverage: unknown"`)
+		assert.Contains(t, buf.String(), `fill="#9f9f9f"`)
+	})
+
+	t.Run("should render shields endpoint", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&BadgeRenderer{Dir: "a/f", Shields: true}).Render(gp, &buf)
+		assert.ErrorContains(t, err, `can't find directory "a/f" for the badge`)
+
+		err = (&BadgeRenderer{Shields: true}).Render(gp, &buf)
+		assert.NoError(t, err)
+		var endpoint ShieldsEndpoint
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &endpoint))
+		assert.Equal(t, ShieldsEndpoint{SchemaVersion: 1, Label: "coverage", Message: "41.7%", Color: "yellow"}, endpoint)
+	})
+}
@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/cancue/covreport/reporter/config"
@@ -21,19 +27,72 @@ type GoProject struct {
 	Dirs     map[string]*GoDir
 	RootPath string
 	Cutlines *config.Cutlines
+	// Resolver resolves the directories of packages. The default resolver is used when nil.
+	Resolver *Resolver
+	// Tolerant keeps files whose sources can't be found or read, instead of failing Parse.
+	// Their views are rendered from the profile only, and Warnings tells what was skipped.
+	Tolerant bool
+	// Warnings are the errors of packages reported by go list and the reasons of the sources skipped in tolerant mode.
+	// Files of packages with errors are kept like in tolerant mode.
+	Warnings []string
+	// Includes are the modules or packages the report is limited to. Every profile is included when empty.
+	Includes []string
+	// Modules are the main modules of a workspace, each shown as a top-level directory under ".".
+	// Parse sets them when the packages belong to more than one main module.
+	Modules []*Module
+	// Module is the main module whose path is stripped from the displayed paths.
+	// Parse sets it when the packages belong to a single main module.
+	Module *Module
+	// Deps is one of DepsModes, the way to handle replaced and vendored modules. DepsInclude is used when empty.
+	Deps string
 }
 
 // Parse parses the input profiles filename and updates the GoProject's coverage report.
 func (gp *GoProject) Parse(input string) error {
-	profiles, err := cover.ParseProfiles(input)
+	file, err := os.Open(input)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+	return gp.ParseReader(file)
+}
 
-	pkgs, err := findPkgs(profiles)
+// ParseReader parses the profiles read from the reader and updates the GoProject's coverage report.
+func (gp *GoProject) ParseReader(rd io.Reader) error {
+	profiles, err := cover.ParseProfilesFromReader(rd)
 	if err != nil {
 		return err
 	}
+	profiles = gp.includedProfiles(profiles)
+
+	resolver := gp.Resolver
+	if resolver == nil {
+		resolver = &Resolver{}
+	}
+	pkgs, err := resolver.FindPkgs(profiles)
+	if err != nil {
+		return err
+	}
+	gp.Warnings = append(gp.Warnings, pkgWarnings(pkgs)...)
+
+	modules := mainModules(pkgs)
+	if len(modules) == 1 {
+		gp.Module = modules[0]
+	}
+	switch gp.Deps {
+	case DepsInclude, "":
+	case DepsExclude:
+		profiles = slices.DeleteFunc(profiles, func(profile *cover.Profile) bool {
+			return pkgs[path.Dir(profile.FileName)].IsLocalDep()
+		})
+	case DepsGroup:
+		modules = append(modules, depModules(pkgs)...)
+	default:
+		return fmt.Errorf("unknown deps mode %q", gp.Deps)
+	}
+	if len(modules) > 1 {
+		gp.Modules = modules
+	}
 
 	for _, profile := range profiles {
 		dir := gp.SafeDir(filepath.Dir(profile.FileName))
@@ -45,11 +104,20 @@ func (gp *GoProject) Parse(input string) error {
 			}
 		}
 		if file == nil {
-			absPath, err := findFile(pkgs, profile.FileName)
-			if err != nil {
+			absPath, err := resolver.FindFile(pkgs, profile.FileName)
+			if err == nil && gp.Tolerant {
+				err = checkSource(absPath)
+			}
+			// Errors of packages are already warned about.
+			pkg := pkgs[path.Dir(profile.FileName)]
+			pkgErr := pkg != nil && pkg.Error != nil
+			if err != nil && !gp.Tolerant && !pkgErr {
 				return err
 			}
-			file = &GoFile{ABSPath: absPath, GoListItem: NewGoListItem(profile.FileName)}
+			file = &GoFile{ABSPath: absPath, SourceErr: err, GoListItem: gp.NewGoListItem(profile.FileName)}
+			if err != nil && !pkgErr {
+				gp.Warnings = append(gp.Warnings, fmt.Sprintf("%s: %v", profile.FileName, err))
+			}
 			dir.AddFile(file)
 		}
 
@@ -65,16 +133,75 @@ func (gp *GoProject) Parse(input string) error {
 	return nil
 }
 
+// includedProfiles returns the profiles of the files within the included modules or packages.
+func (gp *GoProject) includedProfiles(profiles []*cover.Profile) []*cover.Profile {
+	if len(gp.Includes) == 0 {
+		return profiles
+	}
+	var included []*cover.Profile
+	for _, profile := range profiles {
+		for _, include := range gp.Includes {
+			if hasPathPrefix(profile.FileName, include) {
+				included = append(included, profile)
+				break
+			}
+		}
+	}
+	return included
+}
+
+// pkgWarnings returns the errors of the packages reported by go list, sorted by import path.
+func pkgWarnings(pkgs map[string]*Pkg) []string {
+	var warnings []string
+	for importPath, pkg := range pkgs {
+		if pkg != nil && pkg.Error != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", importPath, strings.TrimSpace(pkg.Error.Err)))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+// mainModules returns the distinct main modules of the packages sorted by path.
+func mainModules(pkgs map[string]*Pkg) []*Module {
+	var modules []*Module
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg == nil || pkg.Module == nil || !pkg.Module.Main || seen[pkg.Module.Path] {
+			continue
+		}
+		seen[pkg.Module.Path] = true
+		modules = append(modules, pkg.Module)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules
+}
+
+// checkSource returns an error if the source file can't be read.
+func checkSource(absPath string) error {
+	file, err := os.Open(absPath)
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
 // SafeDir returns a pointer to a GoDir object for the given relative package path.
 func (gp *GoProject) SafeDir(relPkgPath string) *GoDir {
 	if dir, ok := gp.Dirs[relPkgPath]; ok {
 		return dir
 	}
 
-	dir := &GoDir{GoListItem: NewGoListItem(relPkgPath)}
+	dir := &GoDir{GoListItem: gp.NewGoListItem(relPkgPath)}
 	gp.Dirs[relPkgPath] = dir
 
-	parent := gp.SafeDir(filepath.Dir(relPkgPath))
+	parentPath := filepath.Dir(relPkgPath)
+	if module := gp.findModule(relPkgPath); module != nil {
+		// Modules of a workspace are grouped under "." regardless of their paths.
+		dir.Title = moduleTitle(module)
+		parentPath = "."
+	}
+	parent := gp.SafeDir(parentPath)
 	if parent != dir {
 		parent.SubDirs = append(parent.SubDirs, dir)
 	}
@@ -82,6 +209,30 @@ func (gp *GoProject) SafeDir(relPkgPath string) *GoDir {
 	return dir
 }
 
+// NewGoListItem returns a new GoListItem whose displayed path is relative to the module of the project.
+func (gp *GoProject) NewGoListItem(relPkgPath string) *GoListItem {
+	item := NewGoListItem(relPkgPath)
+	if gp.Module == nil {
+		return item
+	}
+	if relPkgPath == gp.Module.Path {
+		item.Path = "."
+	} else if rest, ok := strings.CutPrefix(relPkgPath, gp.Module.Path+"/"); ok {
+		item.Path = rest
+	}
+	return item
+}
+
+// findModule returns the module of the workspace whose path is the relative package path, or nil if there is none.
+func (gp *GoProject) findModule(relPkgPath string) *Module {
+	for _, module := range gp.Modules {
+		if module.Path == relPkgPath {
+			return module
+		}
+	}
+	return nil
+}
+
 // Root returns the root directory of the Go project.
 func (gp *GoProject) Root() *GoDir {
 	return gp.SafeDir(gp.RootPath)
@@ -107,6 +258,38 @@ func (dir *GoDir) Aggregate() {
 	}
 }
 
+// Packages returns the GoDir and its subdirectories that have files, in pre-order.
+func (dir *GoDir) Packages() []*GoDir {
+	var pkgs []*GoDir
+	if len(dir.Files) > 0 {
+		pkgs = append(pkgs, dir)
+	}
+	for _, subDir := range dir.SubDirs {
+		pkgs = append(pkgs, subDir.Packages()...)
+	}
+	return pkgs
+}
+
+// PackageItem returns a copy of the GoListItem of the GoDir counting the statements of its own files only.
+func (dir *GoDir) PackageItem() *GoListItem {
+	item := *dir.GoListItem
+	item.StmtCount, item.StmtCoveredCount = 0, 0
+	for _, file := range dir.Files {
+		item.StmtCount += file.StmtCount
+		item.StmtCoveredCount += file.StmtCoveredCount
+	}
+	return &item
+}
+
+// AllFiles returns the files of the GoDir and its subdirectories, in pre-order.
+func (dir *GoDir) AllFiles() []*GoFile {
+	var files []*GoFile
+	for _, pkg := range dir.Packages() {
+		files = append(files, pkg.Files...)
+	}
+	return files
+}
+
 // AddFile adds a GoFile to the GoDir's list of files.
 func (dir *GoDir) AddFile(file *GoFile) {
 	dir.Files = append(dir.Files, file)
@@ -116,11 +299,50 @@ type GoFile struct {
 	*GoListItem
 	ABSPath string
 	Profile []cover.ProfileBlock
+	// SourceErr is the reason the source of the file is missing in tolerant mode, or nil.
+	SourceErr error
+}
+
+// LineCounts returns the count of the profile block covering each of the first numLines lines of the file,
+// or nil for lines that are not covered by any block.
+func (file *GoFile) LineCounts(numLines int) []*int {
+	counts := make([]*int, numLines)
+	numProfileBlock := len(file.Profile)
+	idxProfile := 0
+	for idx := range counts {
+		lineNumber := idx + 1
+		// Skip the blocks ending before the line, several of which may share a line.
+		for idxProfile < numProfileBlock && file.Profile[idxProfile].EndLine < lineNumber {
+			idxProfile++
+		}
+		if idxProfile < numProfileBlock && file.Profile[idxProfile].StartLine <= lineNumber {
+			counts[idx] = &file.Profile[idxProfile].Count
+		}
+	}
+	return counts
+}
+
+// UncoveredLines returns the ranges of lines of the profile blocks that are not covered,
+// merging the blocks that overlap or are on adjacent lines.
+func (file *GoFile) UncoveredLines() []LineRange {
+	var ranges []LineRange
+	for _, block := range file.Profile {
+		if block.Count > 0 {
+			continue
+		}
+		if last := len(ranges) - 1; last >= 0 && block.StartLine <= ranges[last].End+1 {
+			ranges[last].End = max(ranges[last].End, block.EndLine)
+			continue
+		}
+		ranges = append(ranges, LineRange{Start: block.StartLine, End: block.EndLine})
+	}
+	return ranges
 }
 
 func NewGoListItem(relPkgPath string) *GoListItem {
 	return &GoListItem{
 		RelPkgPath: relPkgPath,
+		Path:       relPkgPath,
 		ID:         uuid.NewSHA1(uuid.Nil, []byte(relPkgPath)).String(),
 		Title:      filepath.Base(relPkgPath),
 	}
@@ -128,8 +350,10 @@ func NewGoListItem(relPkgPath string) *GoListItem {
 
 type GoListItem struct {
 	RelPkgPath string
-	ID         string
-	Title      string
+	// Path is the displayed path, relative to the module of the project if known.
+	Path  string
+	ID    string
+	Title string
 
 	StmtCount        int
 	StmtCoveredCount int
@@ -3,9 +3,11 @@ package internal
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
 )
 
 func TestGoListItemPercent(t *testing.T) {
@@ -79,6 +81,44 @@ func TestSafeDir(t *testing.T) {
 	assert.Equal(t, c, b.SubDirs[0])
 }
 
+func TestSafeDirModules(t *testing.T) {
+	t.Run("should group modules under root", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Modules = []*Module{
+			{Path: "example.com/a", Main: true},
+			{Path: "other.org/b", Main: true},
+			{Path: "example.com/c", Replace: &Module{Path: "../c"}},
+			{Path: "example.com/d", Version: "v1.0.0"},
+		}
+
+		foo := gp.SafeDir("example.com/a/foo")
+		bar := gp.SafeDir("other.org/b/bar")
+		root := gp.Root()
+		assert.Len(t, root.SubDirs, 2)
+		assert.Equal(t, "example.com/a", root.SubDirs[0].Title)
+		assert.Equal(t, foo, root.SubDirs[0].SubDirs[0])
+		assert.Equal(t, "other.org/b", root.SubDirs[1].Title)
+		assert.Equal(t, bar, root.SubDirs[1].SubDirs[0])
+		assert.NotContains(t, gp.Dirs, "example.com")
+
+		assert.Equal(t, "example.com/c (replaced)", gp.SafeDir("example.com/c").Title)
+		assert.Equal(t, "example.com/d (vendored)", gp.SafeDir("example.com/d").Title)
+	})
+}
+
+func TestGoProject_NewGoListItem(t *testing.T) {
+	t.Run("should display paths relative to module", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		assert.Equal(t, "example.com/a/foo", gp.NewGoListItem("example.com/a/foo").Path)
+
+		gp.Module = &Module{Path: "example.com/a"}
+		assert.Equal(t, ".", gp.NewGoListItem("example.com/a").Path)
+		assert.Equal(t, "foo/foo.go", gp.NewGoListItem("example.com/a/foo/foo.go").Path)
+		assert.Equal(t, "example.com/ab", gp.NewGoListItem("example.com/ab").Path)
+		assert.Equal(t, "example.com", gp.NewGoListItem("example.com").Path)
+	})
+}
+
 func TestAggregate(t *testing.T) {
 	gp := NewGoProject(".", nil)
 	a := gp.Root()
@@ -170,3 +210,214 @@ func TestGoProject_Parse(t *testing.T) {
 		})
 	}
 }
+
+func TestGoProject_ParseTolerant(t *testing.T) {
+	curPkg := "github.com/cancue/covreport/reporter/internal"
+	input := filepath.Join(t.TempDir(), "cover.prof")
+	profile := fmt.Sprintf("mode: set\n%s/dirs.go:1.1,2.1 2 1\n%s/not-exist.go:1.1,2.1 3 0\nexample.com/not-exist/foo.go:1.1,2.1 1 0\n", curPkg, curPkg)
+	assert.NoError(t, os.WriteFile(input, []byte(profile), 0o644))
+
+	t.Run("should return error when package is missing", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Strategy: ResolveMod}
+		err := gp.Parse(input)
+		assert.ErrorContains(t, err, "did not find package for example.com/not-exist/foo.go")
+	})
+
+	t.Run("should keep files with missing sources and warn", func(t *testing.T) {
+		gp := NewGoProject(curPkg, nil)
+		gp.Resolver = &Resolver{Strategy: ResolveMod}
+		gp.Tolerant = true
+		err := gp.Parse(input)
+		assert.NoError(t, err)
+
+		root := gp.Root()
+		assert.Len(t, root.Files, 2)
+		assert.Equal(t, 5, root.StmtCount)
+		assert.Equal(t, 2, root.StmtCoveredCount)
+		assert.NoError(t, root.Files[0].SourceErr)
+		assert.Error(t, root.Files[1].SourceErr)
+		assert.Equal(t, 1, gp.SafeDir("example.com/not-exist").Files[0].StmtCount)
+		assert.Len(t, gp.Warnings, 2)
+		assert.Equal(t, "example.com/not-exist/foo.go: did not find package for example.com/not-exist/foo.go", gp.Warnings[0])
+		assert.Contains(t, gp.Warnings[1], curPkg+"/not-exist.go: ")
+	})
+}
+
+func TestGoProject_ParseWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.work":          "go 1.21\n\nuse (\n\t./a\n\t./b\n)\n",
+		"a/go.mod":         "module example.com/a\n",
+		"a/foo/foo.go":     "package foo\n",
+		"b/go.mod":         "module other.org/b\n",
+		"b/bar/bar.go":     "package bar\n",
+		"b/baz/baz.go":     "package baz\n",
+		"cover.prof":       "mode: set\nexample.com/a/foo/foo.go:1.1,2.1 2 1\nother.org/b/bar/bar.go:1.1,2.1 3 0\nother.org/b/baz/baz.go:1.1,2.1 1 1\n",
+		"unused/unused.go": "package unused\n",
+	})
+	input := filepath.Join(dir, "cover.prof")
+
+	t.Run("should show modules as top-level directories with their totals", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Strategy: ResolveMod, Dir: dir}
+		err := gp.Parse(input)
+		assert.NoError(t, err)
+
+		assert.Len(t, gp.Modules, 2)
+		root := gp.Root()
+		assert.Equal(t, root, gp.InitialDir())
+		assert.Len(t, root.SubDirs, 2)
+		assert.Equal(t, "example.com/a", root.SubDirs[0].Title)
+		assert.Equal(t, 2, root.SubDirs[0].StmtCount)
+		assert.Equal(t, "other.org/b", root.SubDirs[1].Title)
+		assert.Equal(t, 4, root.SubDirs[1].StmtCount)
+		assert.Equal(t, 1, root.SubDirs[1].StmtCoveredCount)
+		assert.Equal(t, 6, root.StmtCount)
+	})
+
+	t.Run("should include selected modules only", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Strategy: ResolveMod, Dir: dir}
+		gp.Includes = []string{"other.org/b"}
+		err := gp.Parse(input)
+		assert.NoError(t, err)
+
+		assert.Empty(t, gp.Modules)
+		assert.Equal(t, "other.org/b", gp.Module.Path)
+		assert.Equal(t, 4, gp.Root().StmtCount)
+		assert.Equal(t, "b", gp.InitialDir().Title)
+		assert.Equal(t, ".", gp.InitialDir().Path)
+		assert.Equal(t, "bar/bar.go", gp.InitialDir().SubDirs[0].Files[0].Path)
+	})
+}
+
+func TestGoProject_ParseDeps(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"m/go.mod":       "module example.com/m\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ../dep\n",
+		"m/foo/foo.go":   "package foo\n\nimport _ \"example.com/dep/bar\"\n",
+		"m/cover.prof":   "mode: set\nexample.com/m/foo/foo.go:1.1,2.1 2 1\nexample.com/dep/bar/bar.go:1.1,2.1 3 0\n",
+		"dep/go.mod":     "module example.com/dep\n",
+		"dep/bar/bar.go": "package bar\n",
+	})
+	t.Setenv("GOFLAGS", "-mod=mod")
+	input := filepath.Join(dir, "m", "cover.prof")
+
+	tests := []struct {
+		deps      string
+		wantStmts int
+		wantDirs  []string
+	}{
+		{DepsInclude, 5, []string{"example.com"}},
+		{DepsExclude, 2, []string{"example.com"}},
+		{DepsGroup, 5, []string{"example.com/dep (replaced)", "example.com/m"}},
+	}
+	for _, tc := range tests {
+		t.Run("should handle replaced modules with "+tc.deps, func(t *testing.T) {
+			gp := NewGoProject(".", nil)
+			gp.Resolver = &Resolver{Dir: filepath.Join(dir, "m")}
+			gp.Deps = tc.deps
+			err := gp.Parse(input)
+			assert.NoError(t, err)
+
+			var titles []string
+			for _, subDir := range gp.Root().SubDirs {
+				titles = append(titles, subDir.Title)
+			}
+			assert.Equal(t, tc.wantDirs, titles)
+			assert.Equal(t, tc.wantStmts, gp.Root().StmtCount)
+			assert.Equal(t, "example.com/m", gp.Module.Path)
+		})
+	}
+
+	t.Run("should return error when deps mode is unknown", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Dir: filepath.Join(dir, "m")}
+		gp.Deps = "magic"
+		err := gp.Parse(input)
+		assert.ErrorContains(t, err, `unknown deps mode "magic"`)
+	})
+}
+
+func TestGoProject_ParsePkgErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.21\n",
+		"it/it.go":   "//go:build integration\n\npackage it\n",
+		"cover.prof": "mode: set\nexample.com/m/it/it.go:3.1,4.1 2 1\nexample.com/m/missing/missing.go:1.1,2.1 1 0\n",
+	})
+	input := filepath.Join(dir, "cover.prof")
+
+	t.Run("should warn about package errors instead of failing", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Strategy: ResolveGoList, Dir: dir}
+		err := gp.Parse(input)
+		assert.NoError(t, err)
+
+		assert.Len(t, gp.Warnings, 2)
+		assert.Contains(t, gp.Warnings[0], "example.com/m/it: build constraints exclude all Go files")
+		assert.Contains(t, gp.Warnings[1], "example.com/m/missing: ")
+		assert.NoError(t, gp.SafeDir("example.com/m/it").Files[0].SourceErr)
+		assert.Error(t, gp.SafeDir("example.com/m/missing").Files[0].SourceErr)
+		assert.Equal(t, 3, gp.Root().StmtCount)
+	})
+
+	t.Run("should resolve packages with build tags", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		gp.Resolver = &Resolver{Strategy: ResolveGoList, Dir: dir, Tags: "integration"}
+		err := gp.Parse(input)
+		assert.NoError(t, err)
+
+		assert.Len(t, gp.Warnings, 1)
+		assert.Contains(t, gp.Warnings[0], "example.com/m/missing: ")
+	})
+}
+
+func TestUncoveredLines(t *testing.T) {
+	t.Run("should merge uncovered blocks on overlapping or adjacent lines", func(t *testing.T) {
+		file := &GoFile{Profile: []cover.ProfileBlock{
+			{StartLine: 1, EndLine: 2, Count: 1},
+			{StartLine: 3, EndLine: 5, Count: 0},
+			{StartLine: 5, EndLine: 6, Count: 0},
+			{StartLine: 7, EndLine: 7, Count: 0},
+			{StartLine: 9, EndLine: 10, Count: 0},
+			{StartLine: 10, EndLine: 12, Count: 2},
+		}}
+		assert.Equal(t, []LineRange{{Start: 3, End: 7}, {Start: 9, End: 10}}, file.UncoveredLines())
+		assert.Empty(t, (&GoFile{}).UncoveredLines())
+	})
+}
+
+func TestLineCounts(t *testing.T) {
+	file := &GoFile{
+		Profile: []cover.ProfileBlock{
+			{StartLine: 2, EndLine: 3, Count: 0},
+			{StartLine: 4, EndLine: 4, Count: 5},
+		},
+	}
+
+	counts := file.LineCounts(6)
+	assert.Len(t, counts, 6)
+	assert.Nil(t, counts[0])
+	assert.Equal(t, 0, *counts[1])
+	assert.Equal(t, 0, *counts[2])
+	assert.Equal(t, 5, *counts[3])
+	assert.Nil(t, counts[4])
+	assert.Nil(t, counts[5])
+}
+
+func TestLineCountsSharedLine(t *testing.T) {
+	file := &GoFile{
+		Profile: []cover.ProfileBlock{
+			{StartLine: 1, EndLine: 2, Count: 1},
+			{StartLine: 2, EndLine: 2, Count: 2},
+			{StartLine: 3, EndLine: 3, Count: 0},
+		},
+	}
+
+	counts := file.LineCounts(3)
+	assert.Equal(t, 1, *counts[0])
+	assert.Equal(t, 1, *counts[1])
+	assert.Equal(t, 0, *counts[2])
+}
@@ -21,13 +21,14 @@ import (
 type Pkg struct {
 	ImportPath string
 	Dir        string
+	Module     *Module
 	Error      *struct {
 		Err string
 	}
 }
 
-// findPkgs finds the location of every package we care about by running go list.
-func findPkgs(profiles []*cover.Profile) (map[string]*Pkg, error) {
+// findPkgs finds the location of every package we care about by running go list with the command of goList.
+func findPkgs(profiles []*cover.Profile, goList func(args ...string) *exec.Cmd) (map[string]*Pkg, error) {
 	// Run go list to find the location of every package we care about.
 	pkgs := make(map[string]*Pkg)
 	var list []string
@@ -47,10 +48,7 @@ func findPkgs(profiles []*cover.Profile) (map[string]*Pkg, error) {
 		return pkgs, nil
 	}
 
-	// Note: usually run as "go tool cover" in which case $GOROOT is set,
-	// in which case runtime.GOROOT() does exactly what we want.
-	goTool := filepath.Join(runtime.GOROOT(), "bin/go")
-	cmd := exec.Command(goTool, append([]string{"list", "-e", "-json"}, list...)...)
+	cmd := goList(append([]string{"-e", "-json"}, list...)...)
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 	stdout, err := cmd.Output()
@@ -72,6 +70,14 @@ func findPkgs(profiles []*cover.Profile) (map[string]*Pkg, error) {
 	return pkgs, nil
 }
 
+// goTool returns the go command in PATH, or the one of the GOROOT covreport was built with.
+func goTool() string {
+	if goTool, err := exec.LookPath("go"); err == nil {
+		return goTool
+	}
+	return filepath.Join(runtime.GOROOT(), "bin/go")
+}
+
 // findFile finds the location of the named file in GOROOT, GOPATH etc.
 func findFile(pkgs map[string]*Pkg, file string) (string, error) {
 	if strings.HasPrefix(file, ".") || filepath.IsAbs(file) {
@@ -87,5 +93,5 @@ func findFile(pkgs map[string]*Pkg, file string) (string, error) {
 			return "", errors.New(pkg.Error.Err)
 		}
 	}
-	return "", fmt.Errorf("did not find package for %s in go list output", file)
+	return "", fmt.Errorf("did not find package for %s", file)
 }
@@ -24,7 +24,7 @@ func TestFind(t *testing.T) {
 			{FileName: curFileURI},
 		}
 
-		pkgs, err = findPkgs(profiles)
+		pkgs, err = findPkgs(profiles, (&Resolver{}).goList)
 		assert.NoError(t, err)
 
 		pkg := pkgs[curPkg]
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// GitHubRenderer renders the uncovered lines of a GoProject as warning workflow commands of GitHub Actions,
+// which show up as annotations in the diff of pull requests.
+type GitHubRenderer struct {
+	// RepoRoot is the absolute path of the repository the annotated paths are relative to.
+	RepoRoot string
+	// Changed limits the annotations to the uncovered lines overlapping the changed lines, or is nil for all.
+	Changed ChangedLines
+}
+
+// NewGitHubRenderer returns a new GitHubRenderer for the given configuration.
+func NewGitHubRenderer(cfg *config.Config) (Renderer, error) {
+	root, changed, err := newRepoOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &GitHubRenderer{RepoRoot: root, Changed: changed}, nil
+}
+
+// newRepoOptions returns the repository root and the changed lines of the configuration.
+// The repository root defaults to the one containing the source root.
+func newRepoOptions(cfg *config.Config) (string, ChangedLines, error) {
+	root := cfg.RepoRoot
+	if root == "" {
+		var err error
+		if root, err = FindRepoRoot(cfg.Src); err != nil {
+			return "", nil, err
+		}
+	}
+	if cfg.Changed == "" {
+		return root, nil, nil
+	}
+	changed, err := ReadChangedLines(cfg.Changed)
+	if err != nil {
+		return "", nil, err
+	}
+	return root, changed, nil
+}
+
+// Render writes a warning command for each range of uncovered lines to the provided io.Writer.
+// Files outside of the repository are skipped.
+func (r *GitHubRenderer) Render(gp *GoProject, wr io.Writer) error {
+	bw := bufio.NewWriter(wr)
+	for _, file := range gp.InitialDir().AllFiles() {
+		path, ok := RepoPath(r.RepoRoot, file)
+		if !ok {
+			continue
+		}
+		for _, lines := range file.UncoveredLines() {
+			if r.Changed != nil && !r.Changed.Overlaps(path, lines) {
+				continue
+			}
+			message := fmt.Sprintf("Lines %d-%d are not covered by tests", lines.Start, lines.End)
+			if lines.Start == lines.End {
+				message = fmt.Sprintf("Line %d is not covered by tests", lines.Start)
+			}
+			fmt.Fprintf(bw, "::warning file=%s,line=%d,endLine=%d,title=%s::%s\n",
+				escapeWorkflowProperty(path), lines.Start, lines.End, escapeWorkflowProperty("Uncovered code"), escapeWorkflowData(message))
+		}
+	}
+	return bw.Flush()
+}
+
+// escapeWorkflowData escapes the message of a workflow command.
+func escapeWorkflowData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeWorkflowProperty escapes a property value of a workflow command.
+func escapeWorkflowProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+func TestGitHubRender(t *testing.T) {
+	gp := NewGoProject("example.com/m", nil)
+	for name, blocks := range map[string][]cover.ProfileBlock{
+		"example.com/m/a.go": {
+			{StartLine: 3, EndLine: 5, Count: 0},
+			{StartLine: 6, EndLine: 8, Count: 0},
+			{StartLine: 10, EndLine: 12, Count: 1},
+			{StartLine: 14, EndLine: 14, Count: 0},
+		},
+		"example.com/m/b/b,%.go": {{StartLine: 1, EndLine: 2, Count: 0}},
+	} {
+		file := &GoFile{
+			GoListItem: NewGoListItem(name),
+			ABSPath:    filepath.FromSlash("/repo/" + strings.TrimPrefix(name, "example.com/m/")),
+			Profile:    blocks,
+		}
+		gp.SafeDir(filepath.Dir(name)).AddFile(file)
+	}
+	gp.SafeDir("example.com/m/outside").AddFile(&GoFile{
+		GoListItem: NewGoListItem("example.com/m/outside/c.go"),
+		ABSPath:    filepath.FromSlash("/elsewhere/c.go"),
+		Profile:    []cover.ProfileBlock{{StartLine: 1, EndLine: 2, Count: 0}},
+	})
+
+	t.Run("should warn about every uncovered range of files in repository", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&GitHubRenderer{RepoRoot: filepath.FromSlash("/repo")}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"::warning file=a.go,line=3,endLine=8,title=Uncovered code::Lines 3-8 are not covered by tests\n"+
+			"::warning file=a.go,line=14,endLine=14,title=Uncovered code::Line 14 is not covered by tests\n"+
+			"::warning file=b/b%2C%25.go,line=1,endLine=2,title=Uncovered code::Lines 1-2 are not covered by tests\n", buf.String())
+	})
+
+	t.Run("should warn about changed lines only", func(t *testing.T) {
+		var buf strings.Builder
+		r := &GitHubRenderer{RepoRoot: filepath.FromSlash("/repo"), Changed: ChangedLines{"a.go": {{Start: 8, End: 13}}}}
+		err := r.Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, "::warning file=a.go,line=3,endLine=8,title=Uncovered code::Lines 3-8 are not covered by tests\n", buf.String())
+	})
+}
@@ -2,142 +2,438 @@ package internal
 
 import (
 	"bufio"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/cancue/covreport/reporter/config"
+	"golang.org/x/tools/cover"
 )
 
-// Report generates an HTML report of the GoProject and writes it to the provided io.Writer.
+// HTMLRenderer renders a GoProject as a single-file HTML report.
+type HTMLRenderer struct {
+	// Template is executed against TemplateData. The default template is used when nil.
+	Template *template.Template
+	// CSS is appended to the default styles.
+	CSS string
+	// Meta is displayed in the header of the report.
+	Meta map[string]string
+	// Palette is the name of the color palette, one of Palettes.
+	Palette string
+	// Sidebar enables the tree sidebar navigation.
+	Sidebar bool
+	// Jobs is the number of files loaded concurrently. The number of CPUs is used when it is less than 1.
+	Jobs int
+	// Lazy embeds the lines of files as compressed payloads rendered when their views are opened.
+	Lazy bool
+}
+
+// Palettes are the names of the color palettes of the HTML report.
+var Palettes = []string{"default", "colorblind"}
+
+// NewHTMLRenderer returns a new HTMLRenderer for the given configuration.
+// It loads the custom template and CSS files if they are configured.
+func NewHTMLRenderer(cfg *config.Config) (Renderer, error) {
+	tmpl, err := NewHTMLTemplate(cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var css string
+	if cfg.CSS != "" {
+		src, err := os.ReadFile(cfg.CSS)
+		if err != nil {
+			return nil, fmt.Errorf("can't read %q: %v", cfg.CSS, err)
+		}
+		css = string(src)
+	}
+
+	palette := cfg.Palette
+	if palette == "" {
+		palette = Palettes[0]
+	} else if !slices.Contains(Palettes, palette) {
+		return nil, fmt.Errorf("unknown palette %q, expected one of %s", palette, strings.Join(Palettes, ", "))
+	}
+
+	return &HTMLRenderer{Template: tmpl, CSS: css, Meta: cfg.Meta, Palette: palette, Sidebar: cfg.Sidebar, Jobs: cfg.Jobs, Lazy: cfg.Lazy}, nil
+}
+
+// NewHTMLTemplate parses the default HTML template and then the named custom template file over it, if any.
+// Blocks defined by the custom template override the default ones of the same name.
+// If the custom template has content outside of its definitions, that content replaces the whole document.
+func NewHTMLTemplate(filename string) (*template.Template, error) {
+	tmpl := parseTemplateHTML()
+	if filename == "" {
+		return tmpl, nil
+	}
+
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	custom, err := tmpl.New(filepath.Base(filename)).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("can't parse template %q: %v", filename, err)
+	}
+	if custom.Tree == nil || parse.IsEmptyTree(custom.Tree.Root) {
+		return tmpl, nil
+	}
+	return custom, nil
+}
+
+// parseTemplateHTML parses the default HTML template.
+func parseTemplateHTML() *template.Template {
+	return template.Must(template.New("html").Funcs(template.FuncMap{"href": viewHash}).Parse(templateHTML))
+}
+
+// viewHash returns the link to the view with the given ID within a single-file report.
+func viewHash(id string) string {
+	return "#" + id
+}
+
+// Render generates an HTML report of the GoProject and writes it to the provided io.Writer.
 // The report includes a directory tree of the project's files and directories, along with coverage information.
+// Views are rendered while the tree is walked, so that only a few of them are held in memory at once,
+// unless the template uses Views.
+func (r *HTMLRenderer) Render(gp *GoProject, wr io.Writer) error {
+	tmpl := r.template()
+	data := r.NewTemplateData(gp)
+	wait := data.StreamViews(gp.InitialDir(), r.jobs())
+	if usesViews(tmpl) {
+		if err := data.BufferViews(wait); err != nil {
+			return err
+		}
+		return tmpl.Execute(wr, data)
+	}
+	err := tmpl.Execute(wr, data)
+	if walkErr := wait(); walkErr != nil {
+		return walkErr
+	}
+	return err
+}
+
+// viewsField matches the Views field of TemplateData in a template.
+var viewsField = regexp.MustCompile(`\.Views\b`)
+
+// usesViews reports whether the template or any template associated with it refers to Views.
+func usesViews(tmpl *template.Template) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil && t.Tree.Root != nil && viewsField.MatchString(t.Tree.Root.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTemplateData returns the template data of the GoProject without its views.
+func (r *HTMLRenderer) NewTemplateData(gp *GoProject) *TemplateData {
+	palette := r.Palette
+	if palette == "" {
+		palette = Palettes[0]
+	}
+
+	initialDir := gp.InitialDir()
+	data := &TemplateData{InitialID: initialDir.ID, Cutlines: gp.Cutlines, CSS: r.CSS, Meta: r.Meta, Palette: palette, Lazy: r.Lazy}
+	if r.Sidebar {
+		data.Tree = NewTemplateTreeNodeData(initialDir, rootTitle(initialDir), gp.Cutlines)
+	}
+	return data
+}
+
+// template returns the template of the renderer, or the default template when it is not set.
+func (r *HTMLRenderer) template() *template.Template {
+	if r.Template == nil {
+		return parseTemplateHTML()
+	}
+	return r.Template
+}
+
+// jobs returns the number of files loaded concurrently.
+func (r *HTMLRenderer) jobs() int {
+	if r.Jobs < 1 {
+		return runtime.NumCPU()
+	}
+	return r.Jobs
+}
+
+// Report generates an HTML report of the GoProject and writes it to the provided io.Writer.
 func (gp *GoProject) Report(wr io.Writer) error {
-	tmpl := template.Must(template.New("html").Parse(templateHTML))
+	return (&HTMLRenderer{}).Render(gp, wr)
+}
+
+// errStreamStopped is returned by the walk of StreamViews when the views are no longer received.
+var errStreamStopped = errors.New("stream stopped")
+
+// viewTask is a view being loaded by StreamViews.
+type viewTask struct {
+	view *TemplateViewData
+	err  error
+	done chan struct{}
+}
+
+// StreamViews sets ViewStream to a channel receiving the views of the directory tree, walked in a new goroutine.
+// Up to jobs file views are loaded concurrently, and they are received in the order of WalkDir regardless of jobs.
+// The returned function must be called once the views are no longer received.
+// It stops the walk if it is still running and returns the first error of loading a view, if any.
+func (td *TemplateData) StreamViews(dir *GoDir, jobs int) (wait func() error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	views := make(chan *TemplateViewData)
+	tasks := make(chan *viewTask, jobs)
+	sem := make(chan struct{}, jobs)
+	done := make(chan struct{})
+	errc := make(chan error, 1)
+	var wg sync.WaitGroup
+	td.ViewStream = views
+
+	// The walk starts loading views in order, while at most jobs of them are being loaded.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(tasks)
+		td.walkDir(dir, nil, func(load func() (*TemplateViewData, error)) error {
+			select {
+			case sem <- struct{}{}:
+			case <-done:
+				return errStreamStopped
+			}
+
+			task := &viewTask{done: make(chan struct{})}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				task.view, task.err = load()
+				close(task.done)
+				<-sem
+			}()
 
-	initialDir := gp.Root()
-	if gp.RootPath == "." {
-		for len(initialDir.SubDirs) == 1 && len(initialDir.Files) == 0 {
-			initialDir = initialDir.SubDirs[0]
+			select {
+			case tasks <- task:
+				return nil
+			case <-done:
+				return errStreamStopped
+			}
+		})
+	}()
+
+	// The views are sent in the order their loading was started.
+	go func() {
+		defer close(views)
+		for task := range tasks {
+			<-task.done
+			if task.err != nil {
+				errc <- task.err
+				return
+			}
+			select {
+			case views <- task.view:
+			case <-done:
+				errc <- nil
+				return
+			}
 		}
+		errc <- nil
+	}()
+
+	return func() error {
+		close(done)
+		err := <-errc
+		wg.Wait()
+		return err
 	}
+}
 
-	data := &TemplateData{InitialID: initialDir.ID, Cutlines: gp.Cutlines}
-	if err := data.AddDir(initialDir, nil); err != nil {
+// BufferViews receives the views of ViewStream into Views, calls the wait function returned by StreamViews,
+// and then sets ViewStream to a new channel of the buffered views.
+func (td *TemplateData) BufferViews(wait func() error) error {
+	for view := range td.ViewStream {
+		td.Views = append(td.Views, view)
+	}
+	if err := wait(); err != nil {
 		return err
 	}
+	td.ViewStream = sendViews(td.Views)
+	return nil
+}
 
-	return tmpl.Execute(wr, data)
+// sendViews returns a closed channel of the views.
+func sendViews(views []*TemplateViewData) <-chan *TemplateViewData {
+	ch := make(chan *TemplateViewData, len(views))
+	for _, view := range views {
+		ch <- view
+	}
+	close(ch)
+	return ch
 }
 
-// AddDir adds a directory to the template data.
-func (td *TemplateData) AddDir(dir *GoDir, links []*TemplateLinkData) error {
-	var title string
-	if td.InitialID == dir.ID {
-		if dir.RelPkgPath == "." {
-			title = "root"
-		} else {
-			title = dir.RelPkgPath
+// WalkDir walks the directory tree in depth-first order and calls yield with the view of every directory and file.
+// A directory is yielded before its descendants, and the source of a file is read right before its view is yielded.
+func (td *TemplateData) WalkDir(dir *GoDir, links []*TemplateLinkData, yield func(*TemplateViewData) error) error {
+	return td.walkDir(dir, links, func(load func() (*TemplateViewData, error)) error {
+		view, err := load()
+		if err != nil {
+			return err
 		}
-	} else {
-		title = dir.Title
+		return yield(view)
+	})
+}
+
+// walkDir walks the directory tree in the order of WalkDir and calls yield with a function loading every view.
+func (td *TemplateData) walkDir(dir *GoDir, links []*TemplateLinkData, yield func(load func() (*TemplateViewData, error)) error) error {
+	view := td.NewDirView(dir, links)
+	if err := yield(func() (*TemplateViewData, error) { return view, nil }); err != nil {
+		return err
+	}
+
+	for _, subDir := range dir.SubDirs {
+		if err := td.walkDir(subDir, view.Links, yield); err != nil {
+			return err
+		}
+	}
+	for _, file := range dir.Files {
+		file := file
+		if err := yield(func() (*TemplateViewData, error) { return td.NewFileView(file, view.Links) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewDirView returns the view of a directory with the given parent links.
+func (td *TemplateData) NewDirView(dir *GoDir, links []*TemplateLinkData) *TemplateViewData {
+	title := dir.Title
+	if td.InitialID == dir.ID {
+		title = rootTitle(dir)
 	}
 
 	view := &TemplateViewData{
 		ID:             dir.ID,
-		Links:          append(links, &TemplateLinkData{ID: dir.ID, Title: title}),
+		Links:          append(slices.Clip(links), &TemplateLinkData{ID: dir.ID, Title: title}),
 		NumStmtCovered: dir.StmtCoveredCount,
 		NumStmt:        dir.StmtCount,
 		IsDir:          true,
 		Percent:        fmt.Sprintf("%.1f%%", dir.Percent()),
+		Treemap:        NewTreemap(dir, td.Cutlines),
 	}
-	td.Views = append(td.Views, view)
 
 	view.Items = make([]*TemplateListItemData, 0, len(dir.SubDirs)+len(dir.Files))
 	for _, subDir := range dir.SubDirs {
-		if err := td.AddDir(subDir, view.Links); err != nil {
-			return err
-		}
 		view.Items = append(view.Items, NewTemplateListItemData(subDir.GoListItem, td.Cutlines))
 	}
 	for _, file := range dir.Files {
-		if err := td.AddFile(file, view.Links); err != nil {
-			return err
-		}
 		view.Items = append(view.Items, NewTemplateListItemData(file.GoListItem, td.Cutlines))
 	}
-	return nil
+	return view
 }
 
-// AddFile adds a Go file to the template data with the given links and returns an error if any.
-// The method also generates the HTML-escaped lines of code for the file and adds them to the view data.
-func (td *TemplateData) AddFile(file *GoFile, links []*TemplateLinkData) error {
-	src, err := os.ReadFile(file.ABSPath)
-	if err != nil {
-		return fmt.Errorf("can't read %q: %v", file.RelPkgPath, err)
+// rootTitle returns the title of the directory a report starts from.
+func rootTitle(dir *GoDir) string {
+	if dir.Path == "." {
+		return "root"
 	}
+	return dir.Path
+}
 
+// NewFileView returns the view of a Go file with the given parent links and returns an error if any.
+// The method also generates the HTML-escaped lines of code for the file and adds them to the view data.
+func (td *TemplateData) NewFileView(file *GoFile, links []*TemplateLinkData) (*TemplateViewData, error) {
 	id := file.ID
 	title := file.Title
 	view := &TemplateViewData{
 		ID:             id,
-		Links:          append(links, &TemplateLinkData{ID: id, Title: title}),
+		Links:          append(slices.Clip(links), &TemplateLinkData{ID: id, Title: title}),
 		NumStmtCovered: file.StmtCoveredCount,
 		NumStmt:        file.StmtCount,
 		Percent:        fmt.Sprintf("%.1f%%", file.Percent()),
 	}
-	td.Views = append(td.Views, view)
-	numProfileBlock := len(file.Profile)
-	idxProfile := 0
+	if file.SourceErr != nil {
+		view.Missing = file.SourceErr.Error()
+		view.Blocks = NewTemplateBlocksData(file.Profile)
+		return view, nil
+	}
 
-	var buf strings.Builder
-	dst := bufio.NewWriter(&buf)
-	for idx, line := range strings.Split(string(src), "\n") {
-		lineNumber := idx + 1
-		var count *int
-
-		if idxProfile < numProfileBlock {
-			profile := file.Profile[idxProfile]
-			if profile.EndLine < lineNumber {
-				idxProfile++
-				if idxProfile < numProfileBlock {
-					profile = file.Profile[idxProfile]
-				}
-			}
-			if profile.EndLine >= lineNumber && profile.StartLine <= lineNumber {
-				count = &file.Profile[idxProfile].Count
-			}
+	src, err := os.ReadFile(file.ABSPath)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", file.RelPkgPath, err)
+	}
+	lines := strings.Split(string(src), "\n")
+	counts := file.LineCounts(len(lines))
+	if td.Lazy {
+		if view.Payload, err = NewLinesPayload(lines, counts); err != nil {
+			return nil, err
 		}
+		return view, nil
+	}
 
-		if err := WriteHTMLEscapedLine(dst, lineNumber, count, line); err != nil {
-			return err
+	var buf strings.Builder
+	dst := bufio.NewWriter(&buf)
+	for idx, line := range lines {
+		if err := WriteHTMLEscapedLine(dst, id, idx+1, counts[idx], line); err != nil {
+			return nil, err
 		}
 	}
 	if err := dst.Flush(); err != nil {
-		return err
+		return nil, err
 	}
 	view.Lines = buf.String()
-	return nil
+	return view, nil
+}
+
+// NewTemplateBlocksData converts the profile blocks of a file to the blocks of its placeholder view.
+func NewTemplateBlocksData(blocks []cover.ProfileBlock) []*TemplateBlockData {
+	data := make([]*TemplateBlockData, len(blocks))
+	for i, block := range blocks {
+		data[i] = &TemplateBlockData{
+			Range:     fmt.Sprintf("%d.%d-%d.%d", block.StartLine, block.StartCol, block.EndLine, block.EndCol),
+			NumStmt:   block.NumStmt,
+			Count:     block.Count,
+			Uncovered: block.Count == 0,
+		}
+	}
+	return data
+}
+
+// NewLinesPayload returns the lines and their counts as base64-encoded gzipped JSON,
+// which the report decodes and renders in the browser when the file view is opened.
+func NewLinesPayload(lines []string, counts []*int) (string, error) {
+	var buf strings.Builder
+	enc := base64.NewEncoder(base64.StdEncoding, &buf)
+	zw := gzip.NewWriter(enc)
+	payload := struct {
+		Lines  []string `json:"lines"`
+		Counts []*int   `json:"counts"`
+	}{lines, counts}
+	if err := json.NewEncoder(zw).Encode(payload); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 // NewTemplateListItemData returns a new instance of TemplateListItemData based on the given GoListItem and Cutlines.
 func NewTemplateListItemData(item *GoListItem, cutlines *config.Cutlines) *TemplateListItemData {
-	var className string
 	percent := item.Percent()
 
-	if item.StmtCount > 0 {
-		if percent < cutlines.Warning {
-			className = "danger"
-		} else if percent < cutlines.Safe {
-			className = "warning"
-		} else {
-			className = "safe"
-		}
-	}
-
 	return &TemplateListItemData{
-		ClassName:      className,
+		ClassName:      coverageClassName(item, cutlines),
 		ID:             item.ID,
 		Title:          item.Title,
 		Progress:       fmt.Sprintf("%.1f", percent),
@@ -147,15 +443,31 @@ func NewTemplateListItemData(item *GoListItem, cutlines *config.Cutlines) *Templ
 	}
 }
 
+// coverageClassName returns "safe", "warning" or "danger" by comparing the coverage of the item with the cutlines.
+// It returns an empty string when the item has no statement.
+func coverageClassName(item *GoListItem, cutlines *config.Cutlines) string {
+	if item.StmtCount == 0 {
+		return ""
+	}
+	percent := item.Percent()
+	if percent < cutlines.Warning {
+		return "danger"
+	} else if percent < cutlines.Safe {
+		return "warning"
+	}
+	return "safe"
+}
+
 // WriteHTMLEscapedLine writes an HTML-escaped line to the given bufio.Writer.
-func WriteHTMLEscapedLine(dst *bufio.Writer, lineNumber int, count *int, line string) error {
+// The line number links to the line of the file view with the given ID.
+func WriteHTMLEscapedLine(dst *bufio.Writer, id string, lineNumber int, count *int, line string) error {
 	var err error
 	if count == nil {
-		_, err = fmt.Fprintf(dst, "<div class=\"line-number\">%d</div><div class=\"covered-count\"></div><pre class=\"line\">", lineNumber)
+		_, err = fmt.Fprintf(dst, "<a class=\"line-number\" href=\"#%s:L%d\">%d</a><div class=\"covered-count\"></div><pre class=\"line\">", id, lineNumber, lineNumber)
 	} else if *count == 0 {
-		_, err = fmt.Fprintf(dst, "<div class=\"line-number\">%d</div><div class=\"covered-count uncovered\"></div><pre class=\"line uncovered\">", lineNumber)
+		_, err = fmt.Fprintf(dst, "<a class=\"line-number\" href=\"#%s:L%d\">%d</a><div class=\"covered-count uncovered\"></div><pre class=\"line uncovered\">", id, lineNumber, lineNumber)
 	} else {
-		_, err = fmt.Fprintf(dst, "<div class=\"line-number\">%d</div><div class=\"covered-count covered\">%dx</div><pre class=\"line covered\">", lineNumber, *count)
+		_, err = fmt.Fprintf(dst, "<a class=\"line-number\" href=\"#%s:L%d\">%d</a><div class=\"covered-count covered\">%dx</div><pre class=\"line covered\">", id, lineNumber, lineNumber, *count)
 	}
 	if err != nil {
 		return err
@@ -187,18 +499,29 @@ func WriteHTMLEscapedCode(dst *bufio.Writer, line string) error {
 	return err
 }
 
+// The Template*Data types below are the data passed to HTML templates.
+// Custom templates depend on them, so their fields are a stable contract:
+// fields may be added, but existing ones are not renamed or removed.
+
 // TemplateLinkData represents the data needed for a link in a template.
 type TemplateLinkData struct {
-	ID    string
+	// ID is the ID of the linked view.
+	ID string
+	// Title is the text of the link.
 	Title string
 }
 
 // TemplateListItemData represents the data structure for a single item in the HTML template list.
 type TemplateListItemData struct {
-	ClassName      string
-	ID             string
-	Title          string
-	Progress       string
+	// ClassName is "safe", "warning" or "danger" by the cutlines, or empty when there is no statement.
+	ClassName string
+	// ID is the ID of the view of the item.
+	ID string
+	// Title is the base name of the directory or file.
+	Title string
+	// Progress is the coverage percent formatted for a progress element, e.g. "70.0".
+	Progress string
+	// Percent is the formatted coverage percent, e.g. "70.0%".
 	Percent        string
 	NumStmtCovered int
 	NumStmt        int
@@ -206,21 +529,60 @@ type TemplateListItemData struct {
 
 // TemplateViewData represents the data needed to render a template view.
 type TemplateViewData struct {
-	ID             string
+	// ID is unique per directory or file and is used as the URL hash of the view.
+	ID string
+	// Percent is the formatted coverage percent, e.g. "70.0%".
 	Percent        string
 	NumStmtCovered int
 	NumStmt        int
-	Links          []*TemplateLinkData
-	Items          []*TemplateListItemData
-	Lines          string
-	IsDir          bool
+	// Links are the breadcrumbs from the initial directory to the view itself.
+	Links []*TemplateLinkData
+	// Items are the subdirectories and files of a directory view.
+	Items []*TemplateListItemData
+	// Treemap is the treemap of the descendants of a directory view.
+	Treemap []*TemplateTreemapCellData
+	// Lines is the HTML of the source lines of a file view.
+	Lines string
+	// Payload is the base64-encoded gzipped JSON of the lines and their counts of a file view, instead of Lines.
+	Payload string
+	// Missing is the reason the source of a file view is missing, in which case Blocks is rendered instead of Lines.
+	Missing string
+	// Blocks are the profile blocks of a file view whose source is missing.
+	Blocks []*TemplateBlockData
+	IsDir  bool
+}
+
+// TemplateBlockData represents a profile block of a file view whose source is missing.
+type TemplateBlockData struct {
+	// Range is the formatted position of the block, e.g. "12.5-14.2".
+	Range     string
+	NumStmt   int
+	Count     int
+	Uncovered bool
 }
 
 // TemplateData is a struct that holds data for generating HTML templates.
 type TemplateData struct {
-	Views     []*TemplateViewData
-	InitialID string
-	Cutlines  *config.Cutlines
+	// Views are the views of every directory and file. They are only set when the template refers to them,
+	// in which case every view is loaded before the template is executed.
+	Views []*TemplateViewData
+	// ViewStream receives the same views as Views while the tree is walked. It can be ranged over only once.
+	ViewStream <-chan *TemplateViewData
+	InitialID  string
+	Cutlines   *config.Cutlines
+	// CSS is the custom CSS appended to the default styles.
+	CSS string
+	// Meta is the metadata displayed in the header block.
+	Meta map[string]string
+	// Palette is the name of the color palette, used as the "palette-" class of the body.
+	Palette string
+	// Tree is the root node of the sidebar tree, or nil when the sidebar is disabled.
+	Tree *TemplateTreeNodeData
+	// Lazy is true when file views have Payload instead of Lines.
+	Lazy bool
+	// Assets is true when the styles, the script and the sidebar tree are files shared by the pages of a site,
+	// instead of being inlined.
+	Assets bool
 }
 
 // templateHTML is the HTML template used to generate the coverage report.
@@ -230,16 +592,60 @@ const templateHTML = `
 <html>
 	<head>
 		<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
-		<title>Go Coverage Report</title>
+		<title>{{block "title" .}}Go Coverage Report{{end}}</title>
+		{{- if .Assets}}
+		<link rel="stylesheet" href="style.css">
+		{{- else}}
 		<style>
+		{{- block "style" .}}
+			:root {
+				--fg-color: black;
+				--bg-color: white;
+				--link-color: blue;
+				--muted-color: lightgray;
+				--border-color: gray;
+				--safe-color: green;
+				--safe-bg-color: rgba(0, 255, 0, 0.2);
+				--warning-color: orange;
+				--warning-bg-color: rgba(255, 255, 0, 0.2);
+				--danger-color: red;
+				--danger-bg-color: rgba(255, 0, 0, 0.2);
+				color-scheme: light dark;
+			}
+			@media (prefers-color-scheme: dark) {
+				:root {
+					--fg-color: #d4d4d4;
+					--bg-color: #1e1e1e;
+					--link-color: #75beff;
+					--muted-color: #3c3c3c;
+					--border-color: #6e6e6e;
+					--safe-color: #89d185;
+					--safe-bg-color: rgba(137, 209, 133, 0.15);
+					--warning-color: #e2c08d;
+					--warning-bg-color: rgba(226, 192, 141, 0.15);
+					--danger-color: #f48771;
+					--danger-bg-color: rgba(244, 135, 113, 0.2);
+				}
+			}
+			/* Okabe-Ito colors distinguishable with color vision deficiencies. */
+			.palette-colorblind {
+				--safe-color: rgb(0, 114, 178);
+				--safe-bg-color: rgba(0, 114, 178, 0.2);
+				--warning-color: rgb(230, 159, 0);
+				--warning-bg-color: rgba(230, 159, 0, 0.2);
+				--danger-color: rgb(213, 94, 0);
+				--danger-bg-color: rgba(213, 94, 0, 0.25);
+			}
 			body {
 				font-family: Menlo, monospace;
+				color: var(--fg-color);
+				background-color: var(--bg-color);
 			}
 			a {
 				text-decoration: none;
-				color: blue;
+				color: var(--link-color);
 				&:visited {
-					color: blue;
+					color: var(--link-color);
 				}
 			}
 			progress {
@@ -257,23 +663,23 @@ const templateHTML = `
 			.view .links a:not(:first-child):not(:last-child) {
 				&::after {
 					content: "/";
-					color: black;
+					color: var(--fg-color);
 				}
 			}
 			.view .links a:first-child {
-				border: 1px solid gray;
+				border: 1px solid var(--border-color);
 				border-radius: 4px;
-				background-color: lightgray;
+				background-color: var(--muted-color);
 				padding: 2px 4px;
 			}
 			.view .links *:nth-child(2) {
 				&::before {
 					content: "/";
-					color: black;
+					color: var(--fg-color);
 				}
 			}
 			.view .links span {
-				color: black;
+				color: var(--fg-color);
 				font-weight: bold;
 			}
 			.view .summary {
@@ -289,9 +695,9 @@ const templateHTML = `
 				opacity: 0.8;
 			}
 			.view .summary .stmts {
-				border: 1px solid gray;
+				border: 1px solid var(--border-color);
 				border-radius: 4px;
-				background-color: lightgray;
+				background-color: var(--muted-color);
 				padding: 2px 4px;
 			}
 			.lines {
@@ -312,9 +718,17 @@ const templateHTML = `
 			}
 			.lines .line-number {
 				opacity: 0.8;
+				color: inherit;
+			}
+			.lines .highlight {
+				background-color: var(--muted-color);
+				&.line-number {
+					font-weight: bold;
+					opacity: 1;
+				}
 			}
 			.lines .covered-count {
-				background-color: lightgray;
+				background-color: var(--muted-color);
 			}
 			.lines pre {
 				margin: 0;
@@ -323,11 +737,39 @@ const templateHTML = `
 				height: 1.5em;
 			}
 			.lines .uncovered {
-				background-color: rgba(255, 0, 0, 0.2);
+				background-color: var(--danger-bg-color);
+			}
+			/* Stripes and marks tell the coverage state apart without colors. */
+			.lines pre.uncovered {
+				background-image: repeating-linear-gradient(-45deg, transparent 0 6px, var(--danger-bg-color) 6px 8px);
+			}
+			.lines .covered-count.uncovered::before {
+				content: "\2717";
+				color: var(--danger-color);
 			}
 			.lines .covered-count.covered {
-				background-color: rgba(0, 255, 0, 0.2);
-				color: green;
+				background-color: var(--safe-bg-color);
+				color: var(--safe-color);
+			}
+			.missing {
+				margin: 0 1rem 1rem 1rem;
+				opacity: 0.8;
+			}
+			.blocks {
+				margin: 0 1rem 3rem 1rem;
+				display: grid;
+				grid-template-columns: max-content max-content max-content;
+				gap: 1px 2rem;
+				font-family: monospace;
+			}
+			.blocks .wrapper {
+				display: contents;
+			}
+			.blocks .header {
+				font-weight: bold;
+			}
+			.blocks .uncovered > * {
+				background-color: var(--danger-bg-color);
 			}
 			.items {
 				margin: 0 1rem 3rem 1rem;
@@ -338,23 +780,37 @@ const templateHTML = `
 			.items .wrapper > * {
 				padding: 8px 1rem;
 				&:not(:first-child) {
-					color: black;
+					color: var(--fg-color);
 				}
 			}
 			.items .wrapper.danger > * {
-				background-color: rgba(255, 0, 0, 0.2);
-				--accent-color: red;
+				background-color: var(--danger-bg-color);
+				--accent-color: var(--danger-color);
 			}
 			.items .wrapper.safe > * {
-				background-color: rgba(0, 255, 0, 0.2);
-				--accent-color: green;
+				background-color: var(--safe-bg-color);
+				--accent-color: var(--safe-color);
 			}
 			.items .wrapper.warning > * {
-				background-color: rgba(255, 255, 0, 0.2);
-				--accent-color: orange;
+				background-color: var(--warning-bg-color);
+				--accent-color: var(--warning-color);
+			}
+			.items .wrapper .subpath::before {
+				display: inline-block;
+				width: 1.5em;
+				color: var(--accent-color);
+			}
+			.items .wrapper.safe .subpath::before {
+				content: "\2713";
+			}
+			.items .wrapper.warning .subpath::before {
+				content: "!";
+			}
+			.items .wrapper.danger .subpath::before {
+				content: "\2717";
 			}
 			progress {
-				border: 1px solid black;
+				border: 1px solid var(--fg-color);
 			  &::-webkit-progress-value {
 					background-color: var(--accent-color);
 				}
@@ -365,42 +821,542 @@ const templateHTML = `
 					background-color: var(--accent-color);
 				}
 			  &::-webkit-progress-bar {
-					background-color: white;
+					background-color: var(--bg-color);
 				}
 			  &::-moz-progress-bar {
-					background-color: white;
+					background-color: var(--bg-color);
 				}
 			  &::-progress-bar {
-					background-color: white;
+					background-color: var(--bg-color);
 				}
 			}
 			.items .wrapper {
 				display: contents;
 				text-align: right;
-				border: 1px solid lightgray;
+				border: 1px solid var(--muted-color);
 			}
 			.items .wrapper .subpath {
 				text-align: left;
 			}
+			.meta {
+				display: grid;
+				grid-template-columns: max-content auto;
+				gap: 2px 1rem;
+				padding: 1rem 1rem 0 1rem;
+				font-size: 0.8em;
+			}
+			.meta .label {
+				opacity: 0.8;
+			}
+			.items .wrapper[hidden] {
+				display: none;
+			}
+			.items .wrapper.selected > * {
+				box-shadow: inset 0 0 0 2px var(--link-color);
+			}
+			.lines pre.current {
+				outline: 2px solid var(--danger-color);
+			}
+			.treemap-details {
+				margin: 0 1rem 1rem 1rem;
+				& summary {
+					cursor: pointer;
+					opacity: 0.8;
+					margin-bottom: 8px;
+				}
+			}
+			.treemap {
+				position: relative;
+				/* Must match the ratio of treemapWidth to treemapHeight. */
+				aspect-ratio: 3 / 1;
+				border: 1px solid var(--border-color);
+			}
+			.treemap .cell {
+				position: absolute;
+				box-sizing: border-box;
+				overflow: hidden;
+				white-space: nowrap;
+				text-overflow: ellipsis;
+				padding: 2px 4px;
+				font-size: 0.7em;
+				border: 1px solid var(--bg-color);
+				color: var(--fg-color);
+				background-color: var(--muted-color);
+				&:hover {
+					outline: 2px solid var(--link-color);
+					z-index: 1;
+				}
+				&.safe {
+					background-color: var(--safe-bg-color);
+				}
+				&.warning {
+					background-color: var(--warning-bg-color);
+				}
+				&.danger {
+					background-color: var(--danger-bg-color);
+				}
+				&.depth-2 {
+					font-size: 0.6em;
+				}
+			}
+			.has-sidebar {
+				display: flex;
+				margin: 0;
+				& main {
+					flex: 1;
+					min-width: 0;
+					margin: 8px;
+				}
+			}
+			.sidebar {
+				position: sticky;
+				top: 0;
+				flex: 0 0 20rem;
+				height: 100vh;
+				overflow: auto;
+				box-sizing: border-box;
+				padding: 8px 0;
+				font-size: 0.8em;
+				border-right: 1px solid var(--border-color);
+				& ul {
+					list-style: none;
+					margin: 0;
+					padding-left: 1rem;
+				}
+				& details > summary {
+					cursor: pointer;
+				}
+				& .node {
+					display: inline-flex;
+					gap: 8px;
+					padding: 1px 4px;
+					border-radius: 4px;
+					color: var(--fg-color);
+					&.active {
+						background-color: var(--muted-color);
+						font-weight: bold;
+					}
+				}
+				& .badge {
+					padding: 0 4px;
+					border-radius: 4px;
+					font-size: 0.9em;
+				}
+				& .safe .badge {
+					background-color: var(--safe-bg-color);
+				}
+				& .warning .badge {
+					background-color: var(--warning-bg-color);
+				}
+				& .danger .badge {
+					background-color: var(--danger-bg-color);
+				}
+			}
+			.search {
+				margin: 0 1rem 1rem 1rem;
+				padding: 4px 8px;
+				font-family: inherit;
+				color: var(--fg-color);
+				background-color: var(--bg-color);
+				border: 1px solid var(--border-color);
+				border-radius: 4px;
+			}
+			.help-toggle {
+				position: fixed;
+				right: 1rem;
+				bottom: 1rem;
+				width: 2em;
+				height: 2em;
+				border: 1px solid var(--border-color);
+				border-radius: 50%;
+				font-family: inherit;
+				color: var(--fg-color);
+				background-color: var(--muted-color);
+				cursor: pointer;
+			}
+			.help {
+				position: fixed;
+				inset: 0;
+				display: flex;
+				justify-content: center;
+				align-items: center;
+				background-color: rgba(0, 0, 0, 0.4);
+				&[hidden] {
+					display: none;
+				}
+			}
+			.help-dialog {
+				display: grid;
+				grid-template-columns: max-content auto;
+				gap: 8px 1rem;
+				margin: 0;
+				padding: 1.5rem;
+				border-radius: 4px;
+				background-color: var(--bg-color);
+				& dt {
+					font-weight: bold;
+				}
+				& dd {
+					margin: 0;
+				}
+			}
+		{{end -}}
 		</style>
+		{{- if .CSS}}
+		<style>
+{{.CSS}}
+		</style>
+		{{- end}}
+		{{- end}}
+		{{block "head" .}}{{end}}
 	</head>
-	<body>
-		{{range $idx, $view := .Views}}
-		<div id="{{$view.ID}}" class="view file" style="display:none">
+	<body class="palette-{{.Palette}}{{if .Tree}} has-sidebar{{end}}"{{if .Assets}} data-initial-id="{{.InitialID}}"{{end}}>
+		{{if .Tree}}
+		<nav class="sidebar">
+			{{- if .Assets}}
+			<script src="tree.js"></script>
+			{{- else}}
+			<ul>{{template "tree" .Tree}}</ul>
+			{{- end}}
+		</nav>
+		{{end}}
+		<main>
+		{{block "header" .}}
+		{{- if .Meta}}
+		<div class="meta">
+			{{range $key, $value := .Meta}}
+			<div class="label">{{html $key}}</div>
+			<div class="value">{{html $value}}</div>
+			{{end}}
+		</div>
+		{{- end}}
+		{{end}}
+		{{range $idx, $view := .ViewStream}}
+		{{template "view" $view}}
+		{{end}}
+		{{block "footer" .}}{{end}}
+		</main>
+		{{block "help" .}}
+		<button id="help-toggle" class="help-toggle" title="Keyboard shortcuts">?</button>
+		<div id="help" class="help" hidden>
+			<dl class="help-dialog">
+				<dt>n / p</dt><dd>next / previous uncovered block</dd>
+				<dt>j / k</dt><dd>next / previous row</dd>
+				<dt>enter</dt><dd>open selected row</dd>
+				<dt>u</dt><dd>up one level</dd>
+				<dt>/</dt><dd>filter rows</dd>
+				<dt>?</dt><dd>toggle this help</dd>
+			</dl>
+		</div>
+		{{end}}
+	</body>
+	{{- if .Assets}}
+	<script src="script.js"></script>
+	{{- else}}
+	<script>
+	{{- block "script" .}}
+	const initialID = {{if .Assets}}document.body.dataset.initialId{{else}}'{{.InitialID}}'{{end}};
+
+	// parseHash parses a hash of the form #<viewID> or #<fileID>:L<start>[-L<end>].
+	const parseHash = () => {
+		const hash = window.location.hash.substring(1);
+		const match = hash.match(/^(.+):L(\d+)(?:-L?(\d+))?$/);
+		if (!match) {
+			return { id: hash || initialID };
+		}
+		const start = Number(match[2]);
+		const end = match[3] ? Number(match[3]) : start;
+		return { id: match[1], start: Math.min(start, end), end: Math.max(start, end) };
+	};
+
+	// highlightLines highlights the lines from start to end of the view and returns the first of them.
+	const highlightLines = (view, start, end) => {
+		for (const cell of document.querySelectorAll('.lines .highlight')) {
+			cell.classList.remove('highlight');
+		}
+		if (!start) {
+			return null;
+		}
+		const numbers = view.querySelectorAll('.lines .line-number');
+		for (let n = start; n <= end && n <= numbers.length; n++) {
+			const number = numbers[n - 1];
+			number.classList.add('highlight');
+			number.nextElementSibling.classList.add('highlight');
+			number.nextElementSibling.nextElementSibling.classList.add('highlight');
+		}
+		return numbers[start - 1] || null;
+	};
+
+	// sidebarKey is the local storage key of the IDs of the expanded sidebar directories.
+	const sidebarKey = 'covreport-sidebar';
+
+	const saveSidebar = () => {
+		const ids = [...document.querySelectorAll('.sidebar details[open]')].map((details) => details.dataset.id);
+		try {
+			window.localStorage.setItem(sidebarKey, JSON.stringify(ids));
+		} catch (e) {
+			// The state is not persisted where local storage is unavailable.
+		}
+	};
+
+	const restoreSidebar = () => {
+		let ids = [];
+		try {
+			ids = JSON.parse(window.localStorage.getItem(sidebarKey)) || [];
+		} catch (e) {
+			// The state is not persisted where local storage is unavailable.
+		}
+		for (const id of ids) {
+			const details = document.querySelector('.sidebar details[data-id="' + id + '"]');
+			if (details) {
+				details.open = true;
+			}
+		}
+	};
+
+	// activateNode highlights the sidebar node of the view and expands its ancestors.
+	const activateNode = (id) => {
+		for (const node of document.querySelectorAll('.sidebar .node.active')) {
+			node.classList.remove('active');
+		}
+		const node = document.querySelector('.sidebar .node[data-id="' + id + '"]');
+		if (!node) {
+			return;
+		}
+		node.classList.add('active');
+		for (let details = node.parentElement.closest('details'); details; details = details.parentElement.closest('details')) {
+			details.open = true;
+		}
+		node.scrollIntoView({ block: 'nearest' });
+	};
+
+	restoreSidebar();
+	for (const details of document.querySelectorAll('.sidebar details')) {
+		details.addEventListener('toggle', saveSidebar);
+	}
+
+	// loadLines renders the lines of a file view from its compressed payload, if not rendered yet.
+	const loadLines = async (view) => {
+		const lines = view.querySelector('.lines[data-payload]');
+		if (!lines) {
+			return;
+		}
+		const payload = lines.dataset.payload;
+		lines.removeAttribute('data-payload');
+		const bytes = Uint8Array.from(atob(payload), (c) => c.charCodeAt(0));
+		const stream = new Blob([bytes]).stream().pipeThrough(new DecompressionStream('gzip'));
+		const file = JSON.parse(await new Response(stream).text());
+
+		const fragment = document.createDocumentFragment();
+		file.lines.forEach((line, idx) => {
+			const lineNumber = idx + 1;
+			const count = file.counts[idx];
+			const state = count === null ? '' : count === 0 ? ' uncovered' : ' covered';
+
+			const number = document.createElement('a');
+			number.className = 'line-number';
+			number.href = '#' + view.id + ':L' + lineNumber;
+			number.textContent = lineNumber;
+			const coveredCount = document.createElement('div');
+			coveredCount.className = 'covered-count' + state;
+			if (count > 0) {
+				coveredCount.textContent = count + 'x';
+			}
+			const code = document.createElement('pre');
+			code.className = 'line' + state;
+			code.textContent = line.replaceAll('\t', '    ');
+			fragment.append(number, coveredCount, code);
+		});
+		lines.append(fragment);
+	};
+
+	window.renderView = async () => {
+		const { id, start, end } = parseHash();
+		const target = document.getElementById(id) || document.getElementById(initialID);
+		const changed = target !== window.currentView;
+		if (changed) {
+			for (const view of document.getElementsByClassName('view')) {
+				view.style.display = 'none';
+			};
+			target.style.display = 'block';
+			window.currentView = target;
+			window.selectedRow = -1;
+			window.currentBlock = -1;
+		}
+		activateNode(target.id);
+		await loadLines(target);
+		const line = highlightLines(target, start, end);
+		if (line && changed) {
+			line.scrollIntoView({ block: 'center' });
+		}
+	};
+	window.addEventListener('hashchange', () => {
+		window.renderView();
+	});
+	window.renderView();
+
+	// uncoveredBlocks returns the first line of every run of uncovered lines in the view.
+	const uncoveredBlocks = (view) => {
+		const blocks = [];
+		let prevUncovered = false;
+		for (const line of view.querySelectorAll('.lines pre.line')) {
+			const uncovered = line.classList.contains('uncovered');
+			if (uncovered && !prevUncovered) {
+				blocks.push(line);
+			}
+			prevUncovered = uncovered;
+		}
+		return blocks;
+	};
+
+	const jumpBlock = (delta) => {
+		const blocks = uncoveredBlocks(window.currentView);
+		if (blocks.length === 0) {
+			return;
+		}
+		blocks[window.currentBlock]?.classList.remove('current');
+		// Without a current block, the first jump goes to the first or the last block.
+		const current = window.currentBlock >= 0 ? window.currentBlock : delta > 0 ? -1 : blocks.length;
+		window.currentBlock = (current + delta + blocks.length) % blocks.length;
+		const block = blocks[window.currentBlock];
+		block.classList.add('current');
+		block.scrollIntoView({ block: 'center' });
+	};
+
+	const visibleRows = (view) => [...view.querySelectorAll('.items .wrapper')].filter((row) => !row.hidden);
+
+	const selectRow = (delta) => {
+		const rows = visibleRows(window.currentView);
+		if (rows.length === 0) {
+			return;
+		}
+		rows.forEach((row) => row.classList.remove('selected'));
+		window.selectedRow = Math.min(Math.max(window.selectedRow + delta, 0), rows.length - 1);
+		const row = rows[window.selectedRow];
+		row.classList.add('selected');
+		row.firstElementChild.scrollIntoView({ block: 'nearest' });
+	};
+
+	const openRow = () => {
+		const row = visibleRows(window.currentView)[window.selectedRow];
+		if (row) {
+			window.location.href = row.getAttribute('href');
+		}
+	};
+
+	const goUp = () => {
+		const links = window.currentView.querySelectorAll('.links a');
+		if (links.length > 1) {
+			window.location.href = links[links.length - 2].getAttribute('href');
+		}
+	};
+
+	const toggleHelp = (show) => {
+		const help = document.getElementById('help');
+		if (help) {
+			help.hidden = show === undefined ? !help.hidden : !show;
+		}
+	};
+
+	// Shift-clicking a line number selects the range from the highlighted line.
+	document.addEventListener('click', (event) => {
+		const number = event.target.closest('.lines .line-number');
+		const { start } = parseHash();
+		if (!number || !event.shiftKey || !start) {
+			return;
+		}
+		event.preventDefault();
+		const end = Number(number.textContent);
+		window.location.hash = window.currentView.id + ':L' + Math.min(start, end) + '-L' + Math.max(start, end);
+	});
+
+	for (const search of document.querySelectorAll('.search')) {
+		search.addEventListener('input', () => {
+			const query = search.value.toLowerCase();
+			const view = search.closest('.view');
+			for (const row of view.querySelectorAll('.items .wrapper')) {
+				row.hidden = !row.querySelector('.subpath').textContent.toLowerCase().includes(query);
+				row.classList.remove('selected');
+			}
+			window.selectedRow = -1;
+		});
+	}
+
+	// The help block may be overridden by a custom template.
+	document.getElementById('help')?.addEventListener('click', () => toggleHelp(false));
+	document.getElementById('help-toggle')?.addEventListener('click', () => toggleHelp());
+
+	document.addEventListener('keydown', (event) => {
+		if (event.ctrlKey || event.metaKey || event.altKey) {
+			return;
+		}
+		if (event.target.matches('input')) {
+			if (event.key === 'Escape' || event.key === 'Enter') {
+				event.target.blur();
+			}
+			return;
+		}
+		const actions = {
+			n: () => jumpBlock(1),
+			p: () => jumpBlock(-1),
+			j: () => selectRow(1),
+			k: () => selectRow(-1),
+			Enter: openRow,
+			u: goUp,
+			'/': () => window.currentView.querySelector('.search')?.focus(),
+			'?': () => toggleHelp(),
+			Escape: () => toggleHelp(false),
+		};
+		const action = actions[event.key];
+		if (action) {
+			event.preventDefault();
+			action();
+		}
+	});
+	{{end -}}
+	</script>
+	{{- end}}
+</html>
+{{define "tree"}}
+				<li>
+					{{- if .IsDir}}
+					<details data-id="{{.ID}}">
+						<summary>{{template "tree-node" .}}</summary>
+						<ul>{{range $idx, $child := .Children}}{{template "tree" $child}}{{end}}</ul>
+					</details>
+					{{- else}}
+					{{template "tree-node" .}}
+					{{- end}}
+				</li>
+{{- end}}
+{{define "tree-node"}}<a class="node {{.ClassName}}" href="{{href .ID}}" data-id="{{.ID}}"><span class="title">{{.Title}}</span><span class="badge">{{.Percent}}</span></a>{{end}}
+{{define "view"}}
+		<div id="{{.ID}}" class="view file" style="display:none">
 			<div class="links">
-				{{range $idx, $link := $view.Links}}
-				<a href="#{{$link.ID}}">{{$link.Title}}</a>
+				{{range $idx, $link := .Links}}
+				<a href="{{href $link.ID}}">{{$link.Title}}</a>
 				{{end}}
 			</div>
 			<div class="summary">
-				<div class="percent">{{$view.Percent}}</div>
+				<div class="percent">{{.Percent}}</div>
 				<div class="label">Statements</div>
-				<div class="stmts">{{$view.NumStmtCovered}}/{{$view.NumStmt}}</div>
+				<div class="stmts">{{.NumStmtCovered}}/{{.NumStmt}}</div>
 			</div>
-			{{if $view.IsDir}}
+			{{if .IsDir}}
+			{{if .Treemap}}
+			<details class="treemap-details" open>
+				<summary>Treemap</summary>
+				<div class="treemap">
+					{{range $idx, $cell := .Treemap}}
+					<a class="cell depth-{{$cell.Depth}} {{$cell.ClassName}}" href="{{href $cell.ID}}" title="{{$cell.Title}} {{$cell.Percent}}" style="left:{{printf "%.3f" $cell.X}}%;top:{{printf "%.3f" $cell.Y}}%;width:{{printf "%.3f" $cell.Width}}%;height:{{printf "%.3f" $cell.Height}}%">{{$cell.Title}}</a>
+					{{end}}
+				</div>
+			</details>
+			{{end}}
+			<input class="search" type="search" placeholder="Filter (press /)">
 			<div class="items">
-				{{range $idx, $file := $view.Items}}
-				<a class="wrapper {{$file.ClassName}}" href="#{{$file.ID}}">
+				{{range $idx, $file := .Items}}
+				<a class="wrapper {{$file.ClassName}}" href="{{href $file.ID}}">
 					<div class="subpath">{{$file.Title}}</div>
 					<div class="progress"><progress value="{{$file.Progress}}" max="100"></progress></div>
 					<div class="percent">{{$file.Percent}}</div>
@@ -408,29 +1364,27 @@ const templateHTML = `
 				</a>
 				{{end}}
 			</div>
+			{{else if .Missing}}
+			<div class="missing">Source unavailable: {{html .Missing}}</div>
+			<div class="blocks">
+				<div class="header">Block</div>
+				<div class="header">Statements</div>
+				<div class="header">Count</div>
+				{{range $idx, $block := .Blocks}}
+				<div class="wrapper{{if $block.Uncovered}} uncovered{{end}}">
+					<div class="range">{{$block.Range}}</div>
+					<div class="statements">{{$block.NumStmt}}</div>
+					<div class="count">{{$block.Count}}x</div>
+				</div>
+				{{end}}
+			</div>
+			{{else if .Payload}}
+			<div class="lines" data-payload="{{.Payload}}"></div>
 			{{else}}
 			<div class="lines">
-				{{$view.Lines}}
+				{{.Lines}}
 			</div>
 			{{end}}
 		</div>
-		{{end}}
-	</body>
-	<script>
-	const initialID = '{{.InitialID}}';
-
-	window.renderView = () => {
-		for (const view of document.getElementsByClassName('view')) {
-			view.style.display = 'none';
-		};
-		const id = window.location.hash ? window.location.hash.substring(1) : initialID;
-		const target = document.getElementById(id) || document.getElementById(initialID);
-		target.style.display = 'block';
-	};
-	window.addEventListener('hashchange', () => {
-		window.renderView();
-	});
-	window.renderView();
-	</script>
-</html>
+{{end}}
 `
@@ -2,8 +2,16 @@ package internal
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"testing"
 
@@ -17,9 +25,37 @@ func TestReport(t *testing.T) {
 		gp := NewGoProject("/", &config.Cutlines{Safe: 70, Warning: 40})
 		file := &GoFile{GoListItem: NewGoListItem("not-exist.go")}
 		gp.Root().AddFile(file)
-		err := gp.Report(nil)
+		err := gp.Report(io.Discard)
 		assert.ErrorContains(t, err, `can't read "not-exist.go"`)
 	})
+
+	t.Run("should render placeholder when source is missing", func(t *testing.T) {
+		gp := NewGoProject("/", &config.Cutlines{Safe: 70, Warning: 40})
+		file := &GoFile{
+			GoListItem: NewGoListItem("not-exist.go"),
+			Profile:    []cover.ProfileBlock{{StartLine: 3, StartCol: 2, EndLine: 5, EndCol: 10, NumStmt: 2, Count: 0}},
+			SourceErr:  errors.New("did not find package for not-exist.go"),
+		}
+		gp.Root().AddFile(file)
+		var buf strings.Builder
+		err := gp.Report(&buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "Source unavailable: did not find package for not-exist.go")
+		assert.Contains(t, buf.String(), `<div class="wrapper uncovered">`)
+		assert.Contains(t, buf.String(), `<div class="range">3.2-5.10</div>`)
+	})
+
+	t.Run("should escape reason source is missing", func(t *testing.T) {
+		gp := NewGoProject("/", &config.Cutlines{Safe: 70, Warning: 40})
+		gp.Root().AddFile(&GoFile{
+			GoListItem: NewGoListItem("not-exist.go"),
+			SourceErr:  errors.New(`open <a&b>/"not-exist.go": no such file or directory`),
+		})
+		var buf strings.Builder
+		err := gp.Report(&buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "Source unavailable: open &lt;a&amp;b&gt;/&#34;not-exist.go&#34;: no such file or directory")
+	})
 }
 
 func TestWriteHTMLEscapedCode(t *testing.T) {
@@ -57,9 +93,9 @@ func TestWriteHTMLEscapedLine(t *testing.T) {
 			if tc.count != nil && *tc.count > 0 {
 				count = fmt.Sprintf("%dx", *tc.count)
 			}
-			expected := fmt.Sprintf(`<div class="line-number">%d</div><div class="covered-count%s">%s</div><pre class="line%s">%s</pre>%s`, ln, tc.class, count, tc.class, code, "\n")
+			expected := fmt.Sprintf(`<a class="line-number" href="#file_id:L%d">%d</a><div class="covered-count%s">%s</div><pre class="line%s">%s</pre>%s`, ln, ln, tc.class, count, tc.class, code, "\n")
 
-			err := WriteHTMLEscapedLine(dst, ln, tc.count, code)
+			err := WriteHTMLEscapedLine(dst, "file_id", ln, tc.count, code)
 			assert.NoError(t, err)
 			dst.Flush()
 			assert.Equal(t, expected, buf.String())
@@ -105,7 +141,7 @@ func TestNewTemplateListItemData(t *testing.T) {
 	})
 }
 
-func TestAddFile(t *testing.T) {
+func TestNewFileView(t *testing.T) {
 	_, curFilename, _, ok := runtime.Caller(0)
 	assert.True(t, ok)
 
@@ -130,19 +166,365 @@ func TestAddFile(t *testing.T) {
 		{ID: "link_id_2", Title: "link_title_2"},
 	}
 
-	err := td.AddFile(file, links)
+	view, err := td.NewFileView(file, links)
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, view.Lines)
+	assert.Contains(t, view.Lines, `<a class="line-number" href="#file_id:L1">1</a>`)
+
+	assert.Equal(t, file.ID, view.ID)
+	assert.Len(t, view.Links, 3)
+
+	assert.Equal(t, file.ID, view.Links[2].ID)
+	assert.Equal(t, file.Title, view.Links[2].Title)
+
+	assert.Equal(t, file.StmtCoveredCount, view.NumStmtCovered)
+	assert.Equal(t, file.StmtCount, view.NumStmt)
+	assert.Equal(t, fmt.Sprintf("%.1f%%", file.Percent()), view.Percent)
+}
+
+func TestNewFileViewSharedLine(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "a.go")
+	assert.NoError(t, os.WriteFile(src, []byte("func a() {\n\tif ok { return }\n\tb()\n}"), 0o644))
+
+	file := &GoFile{
+		GoListItem: NewGoListItem("a/a.go"),
+		ABSPath:    src,
+		Profile: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 11, EndLine: 2, EndCol: 8, NumStmt: 1, Count: 1},
+			{StartLine: 2, StartCol: 8, EndLine: 2, EndCol: 17, NumStmt: 1, Count: 1},
+			{StartLine: 3, StartCol: 2, EndLine: 3, EndCol: 5, NumStmt: 1, Count: 0},
+		},
+	}
+	view, err := (&TemplateData{}).NewFileView(file, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, view.Lines, `L2">2</a><div class="covered-count covered">1x</div>`)
+	assert.Contains(t, view.Lines, `L3">3</a><div class="covered-count uncovered"></div>`)
+}
+
+func TestWalkDir(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
+
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	for _, relPkgPath := range []string{"a/b/c/d/e.go", "a/b/c/d/f.go", "a/b/g.go"} {
+		file := &GoFile{ABSPath: curFilename, GoListItem: NewGoListItem(relPkgPath)}
+		gp.SafeDir(filepath.Dir(relPkgPath)).AddFile(file)
+	}
+	td := &TemplateData{InitialID: gp.Root().ID, Cutlines: gp.Cutlines}
+
+	t.Run("should yield directories before their descendants", func(t *testing.T) {
+		var links [][]string
+		err := td.WalkDir(gp.Root(), nil, func(view *TemplateViewData) error {
+			var titles []string
+			for _, link := range view.Links {
+				titles = append(titles, link.Title)
+			}
+			links = append(links, titles)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"a"},
+			{"a", "b"},
+			{"a", "b", "c"},
+			{"a", "b", "c", "d"},
+			{"a", "b", "c", "d", "e.go"},
+			{"a", "b", "c", "d", "f.go"},
+			{"a", "b", "g.go"},
+		}, links)
+	})
+
+	t.Run("should stop at the first error", func(t *testing.T) {
+		var count int
+		err := td.WalkDir(gp.Root(), nil, func(view *TemplateViewData) error {
+			count++
+			if count == 2 {
+				return errStreamStopped
+			}
+			return nil
+		})
+		assert.Equal(t, errStreamStopped, err)
+		assert.Equal(t, 2, count)
+	})
+}
+
+func TestStreamViews(t *testing.T) {
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	gp.SafeDir("a/b").AddFile(&GoFile{GoListItem: NewGoListItem("a/b/not-exist.go")})
+	gp.SafeDir("a/c")
+
+	t.Run("should receive views until the walk fails", func(t *testing.T) {
+		td := &TemplateData{InitialID: gp.Root().ID, Cutlines: gp.Cutlines}
+		wait := td.StreamViews(gp.Root(), 1)
+		var ids []string
+		for view := range td.ViewStream {
+			ids = append(ids, view.ID)
+		}
+		assert.Equal(t, []string{gp.Root().ID, gp.SafeDir("a/b").ID}, ids)
+		assert.ErrorContains(t, wait(), `can't read "a/b/not-exist.go"`)
+	})
+
+	t.Run("should stop walking when views are no longer received", func(t *testing.T) {
+		td := &TemplateData{InitialID: gp.Root().ID, Cutlines: gp.Cutlines}
+		wait := td.StreamViews(gp.Root(), 1)
+		view := <-td.ViewStream
+		assert.Equal(t, gp.Root().ID, view.ID)
+		assert.NoError(t, wait())
+	})
+}
+
+// heapWriter discards what is written and records the peak heap in use while writing.
+type heapWriter struct {
+	writes   int
+	peakHeap uint64
+}
+
+func (w *heapWriter) Write(p []byte) (int, error) {
+	if w.writes%64 == 0 {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		w.peakHeap = max(w.peakHeap, stats.HeapInuse)
+	}
+	w.writes++
+	return len(p), nil
+}
+
+// BenchmarkHTMLRender renders synthetic projects of increasing size, each file with about 40 KB of source.
+// It reports the peak heap in use while rendering beyond the heap of the project itself, collecting garbage often
+// so that the peak is close to the live heap. Since file views are streamed, it stays far below the total source size
+// and only grows with the directory views and treemaps listing every file.
+func BenchmarkHTMLRender(b *testing.B) {
+	src := filepath.Join(b.TempDir(), "source.go")
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("\tfoo%d := bar(%d) // <synthetic & line>", i, i)
+	}
+	if err := os.WriteFile(src, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, numFiles := range []int{100, 1000, 10000} {
+		gp := NewGoProject("root", &config.Cutlines{Safe: 70, Warning: 40})
+		for i := 0; i < numFiles; i++ {
+			relPkgPath := fmt.Sprintf("root/pkg%d/sub%d/file%d.go", i%50, i%7, i)
+			file := &GoFile{ABSPath: src, GoListItem: NewGoListItem(relPkgPath)}
+			for line := 1; line <= len(lines); line += 100 {
+				file.Profile = append(file.Profile, cover.ProfileBlock{StartLine: line, EndLine: line + 4, NumStmt: 1, Count: i % 2})
+			}
+			file.StmtCount = len(file.Profile)
+			gp.SafeDir(filepath.Dir(relPkgPath)).AddFile(file)
+		}
+		gp.Root().Aggregate()
+
+		b.Run(fmt.Sprintf("files=%d", numFiles), func(b *testing.B) {
+			b.ReportAllocs()
+			defer debug.SetGCPercent(debug.SetGCPercent(10))
+			var stats runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			var wr heapWriter
+			for i := 0; i < b.N; i++ {
+				if err := gp.Report(&wr); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(max(wr.peakHeap, stats.HeapInuse)-stats.HeapInuse)/(1<<20), "render-heap-MB")
+		})
+	}
+}
+
+func TestHTMLRenderViews(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
+
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	file := &GoFile{ABSPath: curFilename, GoListItem: NewGoListItem("a/b/html_test.go")}
+	gp.SafeDir("a/b").AddFile(file)
+
+	t.Run("should render custom template using views", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "custom.tmpl")
+		src := `{{len .Views}} {{(index .Views 0).ID}}|{{range .Views}}{{.ID}},{{end}}|{{range .Views}}{{.ID}},{{end}}|{{range .ViewStream}}{{.ID}},{{end}}`
+		assert.NoError(t, os.WriteFile(filename, []byte(src), 0o644))
+		tmpl, err := NewHTMLTemplate(filename)
+		assert.NoError(t, err)
+		assert.True(t, usesViews(tmpl))
+
+		var buf strings.Builder
+		err = (&HTMLRenderer{Template: tmpl}).Render(gp, &buf)
+		assert.NoError(t, err)
+		ids := gp.Root().ID + "," + gp.SafeDir("a/b").ID + "," + file.ID + ","
+		assert.Equal(t, "3 "+gp.Root().ID+"|"+ids+"|"+ids+"|"+ids, buf.String())
+	})
+
+	t.Run("should stream views of default template", func(t *testing.T) {
+		assert.False(t, usesViews(parseTemplateHTML()))
+	})
+}
+
+func TestNewHTMLTemplate(t *testing.T) {
+	data := &TemplateData{InitialID: "initial"}
+	writeTemplate := func(t *testing.T, src string) string {
+		filename := filepath.Join(t.TempDir(), "custom.tmpl")
+		assert.NoError(t, os.WriteFile(filename, []byte(src), 0o644))
+		return filename
+	}
+
+	t.Run("should return default template without custom file", func(t *testing.T) {
+		tmpl, err := NewHTMLTemplate("")
+		assert.NoError(t, err)
+
+		var buf strings.Builder
+		assert.NoError(t, tmpl.Execute(&buf, data))
+		assert.Contains(t, buf.String(), "<title>Go Coverage Report</title>")
+	})
+
+	t.Run("should override defined blocks", func(t *testing.T) {
+		tmpl, err := NewHTMLTemplate(writeTemplate(t, `{{define "title"}}ACME Coverage{{end}}{{define "header"}}<div class="brand">ACME</div>{{end}}`))
+		assert.NoError(t, err)
+
+		var buf strings.Builder
+		assert.NoError(t, tmpl.Execute(&buf, data))
+		assert.Contains(t, buf.String(), "<title>ACME Coverage</title>")
+		assert.Contains(t, buf.String(), `<div class="brand">ACME</div>`)
+		assert.Contains(t, buf.String(), "const initialID = 'initial';")
+	})
+
+	t.Run("should replace whole document", func(t *testing.T) {
+		tmpl, err := NewHTMLTemplate(writeTemplate(t, `<html>{{.InitialID}}{{block "style" .}}{{end}}</html>`))
+		assert.NoError(t, err)
+
+		var buf strings.Builder
+		assert.NoError(t, tmpl.Execute(&buf, data))
+		assert.True(t, strings.HasPrefix(buf.String(), "<html>initial"))
+		assert.Contains(t, buf.String(), "font-family: Menlo, monospace;")
+	})
+
+	t.Run("should return error when cannot read or parse file", func(t *testing.T) {
+		_, err := NewHTMLTemplate("not-exist.tmpl")
+		assert.ErrorContains(t, err, `can't read "not-exist.tmpl"`)
+
+		_, err = NewHTMLTemplate(writeTemplate(t, `{{define "title"}}`))
+		assert.ErrorContains(t, err, "can't parse template")
+	})
+}
+
+func TestHTMLRender(t *testing.T) {
+	t.Run("should render custom css and meta", func(t *testing.T) {
+		cssFile := filepath.Join(t.TempDir(), "custom.css")
+		assert.NoError(t, os.WriteFile(cssFile, []byte("body { color: purple; }"), 0o644))
+
+		r, err := NewHTMLRenderer(&config.Config{CSS: cssFile, Meta: map[string]string{"commit": "<abc>"}})
+		assert.NoError(t, err)
+
+		gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+		var buf strings.Builder
+		assert.NoError(t, r.Render(gp, &buf))
+		assert.Contains(t, buf.String(), "body { color: purple; }")
+		assert.Contains(t, buf.String(), `<div class="label">commit</div>`)
+		assert.Contains(t, buf.String(), `<div class="value">&lt;abc&gt;</div>`)
+	})
+
+	t.Run("should render palette as body class", func(t *testing.T) {
+		gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+		var buf strings.Builder
+		assert.NoError(t, (&HTMLRenderer{}).Render(gp, &buf))
+		assert.Contains(t, buf.String(), `<body class="palette-default">`)
+
+		r, err := NewHTMLRenderer(&config.Config{Palette: "colorblind"})
+		assert.NoError(t, err)
+		buf.Reset()
+		assert.NoError(t, r.Render(gp, &buf))
+		assert.Contains(t, buf.String(), `<body class="palette-colorblind">`)
+	})
+
+	t.Run("should return error when palette is unknown", func(t *testing.T) {
+		_, err := NewHTMLRenderer(&config.Config{Palette: "neon"})
+		assert.ErrorContains(t, err, `unknown palette "neon"`)
+	})
+
+	t.Run("should return error when cannot read css", func(t *testing.T) {
+		_, err := NewHTMLRenderer(&config.Config{CSS: "not-exist.css"})
+		assert.ErrorContains(t, err, `can't read "not-exist.css"`)
+	})
+}
+
+func TestHTMLRenderKeyboardNavigation(t *testing.T) {
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	var buf strings.Builder
+	assert.NoError(t, (&HTMLRenderer{}).Render(gp, &buf))
+
+	assert.Contains(t, buf.String(), `<input class="search" type="search"`)
+	assert.Contains(t, buf.String(), `<div id="help" class="help" hidden>`)
+	assert.Contains(t, buf.String(), `document.addEventListener('keydown'`)
+	assert.Contains(t, buf.String(), `document.getElementById('help')?.addEventListener`)
+	assert.Contains(t, buf.String(), `delta > 0 ? -1 : blocks.length`)
+}
+
+func TestHTMLRenderJobs(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
+	sources, err := filepath.Glob(filepath.Join(filepath.Dir(curFilename), "*.go"))
+	assert.NoError(t, err)
+
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	for i := 0; i < 60; i++ {
+		source := sources[i%len(sources)]
+		relPkgPath := fmt.Sprintf("a/pkg%d/%d_%s", i%4, i, filepath.Base(source))
+		file := &GoFile{ABSPath: source, GoListItem: NewGoListItem(relPkgPath)}
+		file.Profile = []cover.ProfileBlock{{StartLine: i%10 + 1, EndLine: i%10 + 5, NumStmt: 2, Count: i % 3}}
+		file.StmtCount = 2
+		gp.SafeDir(filepath.Dir(relPkgPath)).AddFile(file)
+	}
+	gp.Root().Aggregate()
+
+	t.Run("should render byte-identical output regardless of jobs", func(t *testing.T) {
+		var expected strings.Builder
+		assert.NoError(t, (&HTMLRenderer{Jobs: 1}).Render(gp, &expected))
+
+		for _, jobs := range []int{0, 2, 3, 8, 64} {
+			var actual strings.Builder
+			assert.NoError(t, (&HTMLRenderer{Jobs: jobs}).Render(gp, &actual))
+			assert.True(t, expected.String() == actual.String(), "jobs=%d", jobs)
+		}
+	})
+
+	t.Run("should return the error of the first failing file", func(t *testing.T) {
+		gp.SafeDir("a/pkg1").AddFile(&GoFile{GoListItem: NewGoListItem("a/pkg1/not-exist.go")})
+		gp.SafeDir("a/pkg2").AddFile(&GoFile{GoListItem: NewGoListItem("a/pkg2/not-exist.go")})
+		for _, jobs := range []int{1, 8} {
+			err := (&HTMLRenderer{Jobs: jobs}).Render(gp, io.Discard)
+			assert.ErrorContains(t, err, `can't read "a/pkg1/not-exist.go"`, "jobs=%d", jobs)
+		}
+	})
+}
+
+func TestNewLinesPayload(t *testing.T) {
+	count := 3
+	payload, err := NewLinesPayload([]string{"package foo", "\tbar()"}, []*int{nil, &count})
 	assert.NoError(t, err)
 
-	assert.Len(t, td.Views, 1)
-	assert.NotEmpty(t, td.Views[0].Lines)
+	compressed, err := base64.StdEncoding.DecodeString(payload)
+	assert.NoError(t, err)
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	assert.NoError(t, err)
+	decoded, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"lines": ["package foo", "\tbar()"], "counts": [null, 3]}`, string(decoded))
+}
 
-	assert.Equal(t, file.ID, td.Views[0].ID)
-	assert.Len(t, td.Views[0].Links, 3)
+func TestNewFileViewLazy(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
 
-	assert.Equal(t, file.ID, td.Views[0].Links[2].ID)
-	assert.Equal(t, file.Title, td.Views[0].Links[2].Title)
+	td := &TemplateData{Lazy: true}
+	file := &GoFile{GoListItem: NewGoListItem("pkg/file.go"), ABSPath: curFilename}
+	view, err := td.NewFileView(file, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, view.Lines)
+	assert.NotEmpty(t, view.Payload)
 
-	assert.Equal(t, file.StmtCoveredCount, td.Views[0].NumStmtCovered)
-	assert.Equal(t, file.StmtCount, td.Views[0].NumStmt)
-	assert.Equal(t, fmt.Sprintf("%.1f%%", file.Percent()), td.Views[0].Percent)
+	var buf strings.Builder
+	assert.NoError(t, parseTemplateHTML().ExecuteTemplate(&buf, "view", view))
+	assert.Contains(t, buf.String(), `<div class="lines" data-payload="`+view.Payload+`"></div>`)
 }
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// JSONRenderer renders a GoProject as a JSON document describing the directory tree.
+type JSONRenderer struct{}
+
+// NewJSONRenderer returns a new JSONRenderer for the given configuration.
+func NewJSONRenderer(cfg *config.Config) (Renderer, error) {
+	return &JSONRenderer{}, nil
+}
+
+// Render writes the coverage tree of the GoProject as indented JSON to the provided io.Writer.
+func (r *JSONRenderer) Render(gp *GoProject, wr io.Writer) error {
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(NewJSONDir(gp.InitialDir()))
+}
+
+// JSONItem represents the coverage summary shared by directories and files.
+type JSONItem struct {
+	Path              string  `json:"path"`
+	Statements        int     `json:"statements"`
+	CoveredStatements int     `json:"coveredStatements"`
+	Percent           float64 `json:"percent"`
+}
+
+// JSONDir represents a directory in the JSON report.
+type JSONDir struct {
+	JSONItem
+	Dirs  []*JSONDir  `json:"dirs,omitempty"`
+	Files []*JSONFile `json:"files,omitempty"`
+}
+
+// JSONFile represents a file in the JSON report.
+type JSONFile struct {
+	JSONItem
+	Blocks []*JSONBlock `json:"blocks,omitempty"`
+	// Missing is the reason the source of the file is missing in tolerant mode.
+	Missing string `json:"missing,omitempty"`
+}
+
+// JSONBlock represents a single profile block of a file.
+type JSONBlock struct {
+	StartLine  int `json:"startLine"`
+	StartCol   int `json:"startCol"`
+	EndLine    int `json:"endLine"`
+	EndCol     int `json:"endCol"`
+	Statements int `json:"statements"`
+	Count      int `json:"count"`
+}
+
+// NewJSONItem returns a new JSONItem based on the given GoListItem.
+func NewJSONItem(item *GoListItem) JSONItem {
+	return JSONItem{
+		Path:              item.Path,
+		Statements:        item.StmtCount,
+		CoveredStatements: item.StmtCoveredCount,
+		Percent:           item.Percent(),
+	}
+}
+
+// NewJSONDir recursively converts a GoDir to a JSONDir.
+func NewJSONDir(dir *GoDir) *JSONDir {
+	result := &JSONDir{JSONItem: NewJSONItem(dir.GoListItem)}
+	for _, subDir := range dir.SubDirs {
+		result.Dirs = append(result.Dirs, NewJSONDir(subDir))
+	}
+	for _, file := range dir.Files {
+		result.Files = append(result.Files, NewJSONFile(file))
+	}
+	return result
+}
+
+// NewJSONFile converts a GoFile to a JSONFile.
+func NewJSONFile(file *GoFile) *JSONFile {
+	result := &JSONFile{JSONItem: NewJSONItem(file.GoListItem)}
+	if file.SourceErr != nil {
+		result.Missing = file.SourceErr.Error()
+	}
+	for _, block := range file.Profile {
+		result.Blocks = append(result.Blocks, &JSONBlock{
+			StartLine:  block.StartLine,
+			StartCol:   block.StartCol,
+			EndLine:    block.EndLine,
+			EndCol:     block.EndCol,
+			Statements: block.NumStmt,
+			Count:      block.Count,
+		})
+	}
+	return result
+}
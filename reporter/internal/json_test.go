@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+func TestJSONRender(t *testing.T) {
+	gp := NewGoProject("a", nil)
+	file := &GoFile{
+		GoListItem: NewGoListItem("a/b/c.go"),
+		Profile: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, NumStmt: 2, Count: 1},
+			{StartLine: 5, StartCol: 2, EndLine: 6, EndCol: 4, NumStmt: 2, Count: 0},
+		},
+	}
+	file.StmtCount = 4
+	file.StmtCoveredCount = 2
+	gp.SafeDir("a/b").AddFile(file)
+	gp.Root().Aggregate()
+
+	var buf strings.Builder
+	err := (&JSONRenderer{}).Render(gp, &buf)
+	assert.NoError(t, err)
+
+	var root JSONDir
+	err = json.Unmarshal([]byte(buf.String()), &root)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "a", root.Path)
+	assert.Equal(t, 4, root.Statements)
+	assert.Equal(t, 2, root.CoveredStatements)
+	assert.Equal(t, 50.0, root.Percent)
+	assert.Len(t, root.Dirs, 1)
+	assert.Equal(t, "a/b", root.Dirs[0].Path)
+	assert.Len(t, root.Dirs[0].Files, 1)
+
+	jsonFile := root.Dirs[0].Files[0]
+	assert.Equal(t, "a/b/c.go", jsonFile.Path)
+	assert.Equal(t, []*JSONBlock{
+		{StartLine: 1, StartCol: 2, EndLine: 3, EndCol: 4, Statements: 2, Count: 1},
+		{StartLine: 5, StartCol: 2, EndLine: 6, EndCol: 4, Statements: 2, Count: 0},
+	}, jsonFile.Blocks)
+}
+
+func TestNewJSONFile(t *testing.T) {
+	t.Run("should have reason when source is missing", func(t *testing.T) {
+		file := &GoFile{GoListItem: NewGoListItem("a/b/c.go"), SourceErr: errors.New("not found")}
+		assert.Equal(t, "not found", NewJSONFile(file).Missing)
+
+		file.SourceErr = nil
+		data, err := json.Marshal(NewJSONFile(file))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "missing")
+	})
+}
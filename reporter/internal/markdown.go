@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// markdownEmoji are the emoji of the coverage class names.
+var markdownEmoji = map[string]string{
+	"safe":    "🟢",
+	"warning": "🟡",
+	"danger":  "🔴",
+	"":        "⚪",
+}
+
+// MarkdownRenderer renders a GoProject as a Markdown summary for pull request comments and job summaries.
+type MarkdownRenderer struct {
+	// Baseline is the coverage of a previous report, or nil without deltas.
+	Baseline *Baseline
+	// Worst is the number of the least covered files listed. None are listed when not positive.
+	Worst int
+	// Sort is one of SortModes. SortName is used when empty.
+	Sort string
+}
+
+// NewMarkdownRenderer returns a new MarkdownRenderer for the given configuration.
+func NewMarkdownRenderer(cfg *config.Config) (Renderer, error) {
+	if cfg.Sort != "" && !slices.Contains(SortModes, cfg.Sort) {
+		return nil, fmt.Errorf("unknown sort %q, expected one of %s", cfg.Sort, strings.Join(SortModes, ", "))
+	}
+	r := &MarkdownRenderer{Worst: cfg.Worst, Sort: cfg.Sort}
+	if cfg.Baseline != "" {
+		baseline, err := ReadBaseline(cfg.Baseline)
+		if err != nil {
+			return nil, err
+		}
+		r.Baseline = baseline
+	}
+	return r, nil
+}
+
+// Baseline is the coverage of a previous report.
+type Baseline struct {
+	// Total is the coverage percent of the whole report.
+	Total float64
+	// Percents maps the paths of files and packages to their coverage percents.
+	// The percent of a package counts its own files only.
+	Percents map[string]float64
+}
+
+// ReadBaseline reads the baseline from a JSON report.
+func ReadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	var root JSONDir
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("can't parse %q: %v", filename, err)
+	}
+
+	baseline := &Baseline{Total: root.Percent, Percents: make(map[string]float64)}
+	var walk func(dir *JSONDir)
+	walk = func(dir *JSONDir) {
+		pkg := &GoListItem{}
+		for _, file := range dir.Files {
+			baseline.Percents[file.Path] = file.Percent
+			pkg.StmtCount += file.Statements
+			pkg.StmtCoveredCount += file.CoveredStatements
+		}
+		if len(dir.Files) > 0 {
+			baseline.Percents[dir.Path] = pkg.Percent()
+		}
+		for _, subDir := range dir.Dirs {
+			walk(subDir)
+		}
+	}
+	walk(&root)
+	return baseline, nil
+}
+
+// Render writes the Markdown summary of the GoProject to the provided io.Writer.
+func (r *MarkdownRenderer) Render(gp *GoProject, wr io.Writer) error {
+	initialDir := gp.InitialDir()
+	bw := bufio.NewWriter(wr)
+
+	fmt.Fprintf(bw, "## Coverage report\n\n")
+	fmt.Fprintf(bw, "%s **%.1f%%** of statements covered (%s)", r.emoji(gp, initialDir.GoListItem), initialDir.Percent(), textStmts(initialDir.GoListItem))
+	if r.Baseline != nil {
+		fmt.Fprintf(bw, ", %+.1f%% against the baseline", initialDir.Percent()-r.Baseline.Total)
+	}
+	fmt.Fprintf(bw, "\n\n")
+
+	pkgs := initialDir.Packages()
+	items := make([]*GoListItem, len(pkgs))
+	for i, pkg := range pkgs {
+		items[i] = pkg.PackageItem()
+	}
+	if r.Sort == SortCoverage {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Percent() < items[j].Percent() })
+	} else {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	}
+	r.writeTable(bw, gp, "Package", items)
+
+	if files := r.worstFiles(initialDir); len(files) > 0 {
+		fmt.Fprintf(bw, "\n<details>\n<summary>%d least covered files</summary>\n\n", len(files))
+		r.writeTable(bw, gp, "File", files)
+		fmt.Fprintf(bw, "\n</details>\n")
+	}
+	return bw.Flush()
+}
+
+// writeTable writes a table of the items with a column of deltas when there is a baseline.
+func (r *MarkdownRenderer) writeTable(bw *bufio.Writer, gp *GoProject, title string, items []*GoListItem) {
+	if r.Baseline != nil {
+		fmt.Fprintf(bw, "| | %s | Statements | Coverage | Delta |\n|---|---|--:|--:|--:|\n", title)
+	} else {
+		fmt.Fprintf(bw, "| | %s | Statements | Coverage |\n|---|---|--:|--:|\n", title)
+	}
+	for _, item := range items {
+		fmt.Fprintf(bw, "| %s | `%s` | %s | %.1f%% |", r.emoji(gp, item), item.Path, textStmts(item), item.Percent())
+		if r.Baseline != nil {
+			fmt.Fprintf(bw, " %s |", r.delta(item))
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+}
+
+// worstFiles returns the least covered files with statements under the directory, at most Worst of them.
+func (r *MarkdownRenderer) worstFiles(dir *GoDir) []*GoListItem {
+	if r.Worst <= 0 {
+		return nil
+	}
+	var items []*GoListItem
+	for _, file := range dir.AllFiles() {
+		if file.StmtCount > 0 {
+			items = append(items, file.GoListItem)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Percent() < items[j].Percent() })
+	return items[:min(len(items), r.Worst)]
+}
+
+// emoji returns the emoji of the coverage class of the item.
+func (r *MarkdownRenderer) emoji(gp *GoProject, item *GoListItem) string {
+	if gp.Cutlines == nil {
+		return markdownEmoji[""]
+	}
+	return markdownEmoji[coverageClassName(item, gp.Cutlines)]
+}
+
+// delta returns the difference of the coverage percent of the item from the baseline, e.g. "+1.5%",
+// or "new" if the baseline does not have the item.
+func (r *MarkdownRenderer) delta(item *GoListItem) string {
+	percent, ok := r.Baseline.Percents[item.Path]
+	if !ok {
+		return "new"
+	}
+	return fmt.Sprintf("%+.1f%%", item.Percent()-percent)
+}
@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownRender(t *testing.T) {
+	gp := newTextTestProject()
+
+	t.Run("should render totals and packages", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&MarkdownRenderer{}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"## Coverage report\n\n"+
+			"🟡 **41.7%** of statements covered (10/24)\n\n"+
+			"| | Package | Statements | Coverage |\n"+
+			"|---|---|--:|--:|\n"+
+			"| 🔴 | `a` | 1/10 | 10.0% |\n"+
+			"| 🟡 | `a/b` | 5/10 | 50.0% |\n"+
+			"| 🟢 | `a/b/d` | 4/4 | 100.0% |\n", buf.String())
+	})
+
+	t.Run("should render deltas and worst files", func(t *testing.T) {
+		r := &MarkdownRenderer{
+			Baseline: &Baseline{Total: 50, Percents: map[string]float64{"a": 20, "a/b": 50, "a/f.go": 20}},
+			Worst:    2,
+			Sort:     SortCoverage,
+		}
+		var buf strings.Builder
+		err := r.Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"## Coverage report\n\n"+
+			"🟡 **41.7%** of statements covered (10/24), -8.3% against the baseline\n\n"+
+			"| | Package | Statements | Coverage | Delta |\n"+
+			"|---|---|--:|--:|--:|\n"+
+			"| 🔴 | `a` | 1/10 | 10.0% | -10.0% |\n"+
+			"| 🟡 | `a/b` | 5/10 | 50.0% | +0.0% |\n"+
+			"| 🟢 | `a/b/d` | 4/4 | 100.0% | new |\n"+
+			"\n<details>\n<summary>2 least covered files</summary>\n\n"+
+			"| | File | Statements | Coverage | Delta |\n"+
+			"|---|---|--:|--:|--:|\n"+
+			"| 🔴 | `a/f.go` | 1/10 | 10.0% | -10.0% |\n"+
+			"| 🟡 | `a/b/c.go` | 5/10 | 50.0% | new |\n"+
+			"\n</details>\n", buf.String())
+	})
+}
+
+func TestReadBaseline(t *testing.T) {
+	t.Run("should read percents of packages and files from json report", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&JSONRenderer{}).Render(newTextTestProject(), &buf)
+		assert.NoError(t, err)
+		filename := filepath.Join(t.TempDir(), "cover.json")
+		assert.NoError(t, os.WriteFile(filename, []byte(buf.String()), 0o644))
+
+		baseline, err := ReadBaseline(filename)
+		assert.NoError(t, err)
+		assert.InDelta(t, 41.67, baseline.Total, 0.01)
+		assert.Equal(t, map[string]float64{
+			"a":          10,
+			"a/f.go":     10,
+			"a/b":        50,
+			"a/b/c.go":   50,
+			"a/b/d":      100,
+			"a/b/d/e.go": 100,
+		}, baseline.Percents)
+	})
+
+	t.Run("should return error when baseline is invalid", func(t *testing.T) {
+		_, err := NewMarkdownRenderer(&config.Config{Baseline: "not-exist.json"})
+		assert.ErrorContains(t, err, `can't read "not-exist.json"`)
+
+		filename := filepath.Join(t.TempDir(), "cover.json")
+		assert.NoError(t, os.WriteFile(filename, []byte("{"), 0o644))
+		_, err = ReadBaseline(filename)
+		assert.ErrorContains(t, err, "can't parse")
+	})
+}
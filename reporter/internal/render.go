@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// Renderer renders a parsed GoProject to the provided io.Writer.
+type Renderer interface {
+	Render(gp *GoProject, wr io.Writer) error
+}
+
+// DirRenderer is a Renderer whose output is a directory rather than a single file.
+type DirRenderer interface {
+	Renderer
+	RenderDir(gp *GoProject, dir string) error
+}
+
+// RendererFactory creates a Renderer configured by the given configuration.
+type RendererFactory func(cfg *config.Config) (Renderer, error)
+
+// renderers is the registry of available renderers keyed by format name.
+var renderers = map[string]RendererFactory{
+	"badge":   NewBadgeRenderer,
+	"github":  NewGitHubRenderer,
+	"html":    NewHTMLRenderer,
+	"json":    NewJSONRenderer,
+	"md":      NewMarkdownRenderer,
+	"sarif":   NewSARIFRenderer,
+	"shields": NewShieldsRenderer,
+	"site":    NewSiteRenderer,
+	"sonar":   NewSonarRenderer,
+	"text":    NewTextRenderer,
+}
+
+// NewRenderer returns the Renderer registered for the given format.
+func NewRenderer(format string, cfg *config.Config) (Renderer, error) {
+	factory, ok := renderers[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+	return factory(cfg)
+}
+
+// Formats returns the sorted names of all registered formats.
+func Formats() []string {
+	formats := make([]string, 0, len(renderers))
+	for format := range renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// InitialDir returns the directory a report starts from.
+// When the root path is ".", single-child directories without files are skipped.
+func (gp *GoProject) InitialDir() *GoDir {
+	initialDir := gp.Root()
+	if gp.RootPath == "." {
+		for len(initialDir.SubDirs) == 1 && len(initialDir.Files) == 0 {
+			initialDir = initialDir.SubDirs[0]
+		}
+	}
+	return initialDir
+}
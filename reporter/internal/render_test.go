@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRenderer(t *testing.T) {
+	t.Run("should return renderer registered for format", func(t *testing.T) {
+		r, err := NewRenderer("html", &config.Config{})
+		assert.NoError(t, err)
+		assert.IsType(t, &HTMLRenderer{}, r)
+
+		r, err = NewRenderer("json", &config.Config{})
+		assert.NoError(t, err)
+		assert.IsType(t, &JSONRenderer{}, r)
+	})
+
+	t.Run("should return error when format is unknown", func(t *testing.T) {
+		_, err := NewRenderer("xml", &config.Config{})
+		assert.ErrorContains(t, err, `unknown output format "xml"`)
+	})
+}
+
+func TestFormats(t *testing.T) {
+	assert.Equal(t, []string{"badge", "github", "html", "json", "md", "sarif", "shields", "site", "sonar", "text"}, Formats())
+}
+
+func TestInitialDir(t *testing.T) {
+	t.Run("should skip single-child directories when root is current dir", func(t *testing.T) {
+		gp := NewGoProject(".", nil)
+		b := gp.SafeDir("a/b")
+		b.AddFile(&GoFile{GoListItem: NewGoListItem("a/b/c.go")})
+		assert.Equal(t, b, gp.InitialDir())
+	})
+
+	t.Run("should start from root when root is set", func(t *testing.T) {
+		gp := NewGoProject("a", nil)
+		gp.SafeDir("a/b").AddFile(&GoFile{GoListItem: NewGoListItem("a/b/c.go")})
+		assert.Equal(t, gp.Root(), gp.InitialDir())
+	})
+}
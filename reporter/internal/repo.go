@@ -0,0 +1,154 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FindRepoRoot returns the absolute path of the repository containing the directory, that is the nearest directory
+// with a .git entry. It returns the directory itself when there is none.
+func FindRepoRoot(dir string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d, nil
+		}
+		if d == filepath.Dir(d) {
+			return dir, nil
+		}
+	}
+}
+
+// RepoPath returns the slash-separated path of the file relative to the repository root,
+// or false if the file is not in the repository.
+func RepoPath(root string, file *GoFile) (string, bool) {
+	if file.ABSPath == "" || file.SourceErr != nil {
+		return "", false
+	}
+	absPath, err := filepath.Abs(file.ABSPath)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// LineRange is an inclusive range of line numbers.
+type LineRange struct {
+	Start, End int
+}
+
+// ChangedLines maps the repository-relative paths of files to the ranges of their added or modified lines.
+type ChangedLines map[string][]LineRange
+
+// Overlaps reports whether any changed line of the file is within the range.
+func (changed ChangedLines) Overlaps(path string, lines LineRange) bool {
+	for _, r := range changed[path] {
+		if r.Start <= lines.End && lines.Start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadChangedLines reads the added lines from a unified diff such as the output of 'git diff',
+// whose new file names are relative to the repository root.
+func ReadChangedLines(filename string) (ChangedLines, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	defer file.Close()
+
+	changed := make(ChangedLines)
+	var path string
+	var oldLeft, newLeft, newLine int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if oldLeft > 0 || newLeft > 0 {
+			// A line of the body of a hunk.
+			switch {
+			case strings.HasPrefix(line, "+"):
+				changed.add(path, newLine)
+				newLine++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+			case strings.HasPrefix(line, `\`):
+				// No newline at end of file.
+			default:
+				newLine++
+				newLeft--
+				oldLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if tab := strings.IndexByte(path, '\t'); tab >= 0 {
+				path = path[:tab]
+			}
+		case strings.HasPrefix(line, "@@ "):
+			// @@ -start[,count] +start[,count] @@
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("can't parse %q: invalid hunk %q", filename, line)
+			}
+			_, oldCount, err1 := parseHunkRange(fields[1], "-")
+			start, newCount, err2 := parseHunkRange(fields[2], "+")
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("can't parse %q: invalid hunk %q", filename, line)
+			}
+			oldLeft, newLeft, newLine = oldCount, newCount, start
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	return changed, nil
+}
+
+// add adds the line to the changed lines of the file, extending the last range if the line follows it.
+func (changed ChangedLines) add(path string, line int) {
+	ranges := changed[path]
+	if last := len(ranges) - 1; last >= 0 && ranges[last].End == line-1 {
+		ranges[last].End = line
+		return
+	}
+	changed[path] = append(ranges, LineRange{Start: line, End: line})
+}
+
+// parseHunkRange parses a range of a hunk header such as "+12,3", whose count defaults to 1.
+func parseHunkRange(field string, sign string) (start int, count int, err error) {
+	if !strings.HasPrefix(field, sign) {
+		return 0, 0, fmt.Errorf("invalid range %q", field)
+	}
+	startText, countText, hasCount := strings.Cut(field[1:], ",")
+	if start, err = strconv.Atoi(startText); err != nil {
+		return 0, 0, err
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil {
+			return 0, 0, err
+		}
+	}
+	return start, count, nil
+}
@@ -0,0 +1,105 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindRepoRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"repo/.git/HEAD": "ref: refs/heads/main\n", "repo/a/b/c.go": "package b\n"})
+
+	t.Run("should find nearest directory with .git", func(t *testing.T) {
+		root, err := FindRepoRoot(filepath.Join(dir, "repo", "a", "b"))
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(dir, "repo"), root)
+	})
+
+	t.Run("should return directory itself without .git", func(t *testing.T) {
+		root, err := FindRepoRoot(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, dir, root)
+	})
+}
+
+func TestRepoPath(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+
+	t.Run("should return slash-separated path relative to root", func(t *testing.T) {
+		path, ok := RepoPath(root, &GoFile{ABSPath: filepath.FromSlash("/repo/a/b.go")})
+		assert.True(t, ok)
+		assert.Equal(t, "a/b.go", path)
+	})
+
+	t.Run("should skip files outside of repository or without source", func(t *testing.T) {
+		_, ok := RepoPath(root, &GoFile{ABSPath: filepath.FromSlash("/repository/a.go")})
+		assert.False(t, ok)
+
+		_, ok = RepoPath(root, &GoFile{ABSPath: filepath.FromSlash("/repo/a.go"), SourceErr: errors.New("missing")})
+		assert.False(t, ok)
+
+		_, ok = RepoPath(root, &GoFile{})
+		assert.False(t, ok)
+	})
+}
+
+func TestReadChangedLines(t *testing.T) {
+	t.Run("should read added lines of unified diff", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "changes.diff")
+		diff := "diff --git a/a.go b/a.go\n" +
+			"index 1111111..2222222 100644\n" +
+			"--- a/a.go\n" +
+			"+++ b/a.go\n" +
+			"@@ -1,4 +1,5 @@\n" +
+			" package a\n" +
+			"-var x = 1\n" +
+			"+var x = 2\n" +
+			"+var y = 3\n" +
+			" \n" +
+			"+++ this is an added line\n" +
+			" func f() {}\n" +
+			"@@ -20 +21,0 @@\n" +
+			"-removed\n" +
+			"@@ -30 +30 @@\n" +
+			"-old\n" +
+			"+new\n" +
+			"\\ No newline at end of file\n" +
+			"diff --git a/gone.go b/gone.go\n" +
+			"--- a/gone.go\n" +
+			"+++ /dev/null\n" +
+			"@@ -1 +0,0 @@\n" +
+			"-package gone\n" +
+			"diff --git a/b/new.go b/b/new.go\n" +
+			"--- /dev/null\n" +
+			"+++ b/b/new.go\t2024-01-01\n" +
+			"@@ -0,0 +1,2 @@\n" +
+			"+package b\n" +
+			"+\n"
+		assert.NoError(t, os.WriteFile(filename, []byte(diff), 0o644))
+
+		changed, err := ReadChangedLines(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, ChangedLines{
+			"a.go":     {{Start: 2, End: 3}, {Start: 5, End: 5}, {Start: 30, End: 30}},
+			"b/new.go": {{Start: 1, End: 2}},
+		}, changed)
+
+		assert.True(t, changed.Overlaps("a.go", LineRange{Start: 4, End: 6}))
+		assert.False(t, changed.Overlaps("a.go", LineRange{Start: 6, End: 29}))
+		assert.False(t, changed.Overlaps("c.go", LineRange{Start: 1, End: 100}))
+	})
+
+	t.Run("should return error when diff is invalid", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "changes.diff")
+		assert.NoError(t, os.WriteFile(filename, []byte("+++ b/a.go\n@@ -1 +x @@\n"), 0o644))
+		_, err := ReadChangedLines(filename)
+		assert.ErrorContains(t, err, `invalid hunk "@@ -1 +x @@"`)
+
+		_, err = ReadChangedLines("not-exist.diff")
+		assert.ErrorContains(t, err, `can't read "not-exist.diff"`)
+	})
+}
@@ -0,0 +1,424 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cancue/covreport/reporter/config"
+	"golang.org/x/tools/cover"
+)
+
+const (
+	// ResolveAuto resolves packages from go.work and go.mod, and runs go list for the rest.
+	ResolveAuto = "auto"
+	// ResolveMod resolves packages from go.work and go.mod only, without a Go toolchain.
+	ResolveMod = "mod"
+	// ResolveGoList resolves packages by running go list only.
+	ResolveGoList = "golist"
+)
+
+// ResolveStrategies are the names of the strategies to resolve the directories of packages.
+var ResolveStrategies = []string{ResolveAuto, ResolveMod, ResolveGoList}
+
+// Resolver resolves the directories of the packages of profiles.
+type Resolver struct {
+	// Strategy is one of ResolveStrategies. ResolveAuto is used when empty.
+	Strategy string
+	// Dir is where go.work or go.mod is looked up from, go list is run in and relative source paths are read from.
+	// The current directory is used when empty.
+	Dir string
+	// SourceMap rewrites the path prefixes of source files. The longest matching prefix wins.
+	SourceMap []*config.SourceMapping
+	// Go is the path of the go command. The go command in PATH is used when empty.
+	Go string
+	// Tags are the comma-separated build tags passed to go list.
+	Tags string
+	// GoFlags, GOOS and GOARCH override the environment variables of go list when not empty.
+	GoFlags string
+	GOOS    string
+	GOARCH  string
+
+	// listErr is the error of go list falling back from go.mod and go.work, reported with the files it didn't find.
+	listErr error
+}
+
+const (
+	// DepsInclude includes replaced and vendored modules in the tree by their import paths.
+	DepsInclude = "include"
+	// DepsExclude excludes replaced and vendored modules from the report.
+	DepsExclude = "exclude"
+	// DepsGroup shows each replaced and vendored module as a top-level directory, like the modules of a workspace.
+	DepsGroup = "group"
+)
+
+// DepsModes are the names of the ways to handle replaced and vendored modules.
+var DepsModes = []string{DepsInclude, DepsExclude, DepsGroup}
+
+// Module describes a Go module, compatible with the Module of the JSON output from 'go list'.
+type Module struct {
+	Path    string
+	Version string
+	// Replace is the module replacing this module, if any.
+	Replace *Module
+	Dir     string
+	Main    bool
+}
+
+// IsLocalReplace reports whether the module is replaced by a local directory.
+func (m *Module) IsLocalReplace() bool {
+	return m.Replace != nil && m.Replace.Version == ""
+}
+
+// IsLocalDep reports whether the package belongs to a dependency that is replaced by a local directory or vendored.
+// Its files are in the local tree even though it is not a main module.
+func (pkg *Pkg) IsLocalDep() bool {
+	if pkg == nil || pkg.Module == nil || pkg.Module.Main {
+		return false
+	}
+	return pkg.Module.IsLocalReplace() || slices.Contains(strings.Split(filepath.ToSlash(pkg.Dir), "/"), "vendor")
+}
+
+// depModules returns the distinct modules of the local dependencies of the packages sorted by path.
+func depModules(pkgs map[string]*Pkg) []*Module {
+	var modules []*Module
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if !pkg.IsLocalDep() || seen[pkg.Module.Path] {
+			continue
+		}
+		seen[pkg.Module.Path] = true
+		modules = append(modules, pkg.Module)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules
+}
+
+// moduleTitle returns the title of the top-level directory of the module.
+func moduleTitle(module *Module) string {
+	switch {
+	case module.Main:
+		return module.Path
+	case module.IsLocalReplace():
+		return module.Path + " (replaced)"
+	default:
+		return module.Path + " (vendored)"
+	}
+}
+
+// FindPkgs finds the location of every package of the profiles with the strategy of the resolver.
+// Since the go command, build tags and environment only affect go list, ResolveAuto lists every package with go list
+// when any of them is set, and ResolveMod returns an error.
+func (r *Resolver) FindPkgs(profiles []*cover.Profile) (map[string]*Pkg, error) {
+	switch r.Strategy {
+	case ResolveGoList:
+		return findPkgs(profiles, r.goList)
+	case ResolveAuto, "":
+		if r.hasGoListOptions() {
+			return findPkgs(profiles, r.goList)
+		}
+	case ResolveMod:
+		if r.hasGoListOptions() {
+			return nil, fmt.Errorf("resolver %q can't be used with the go command, tags, GOFLAGS, GOOS or GOARCH of go list", r.Strategy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown resolver %q", r.Strategy)
+	}
+
+	modules, err := FindModules(r.Dir)
+	if err != nil {
+		return nil, err
+	}
+	pkgs := findModPkgs(profiles, modules)
+	if r.Strategy == ResolveMod {
+		return pkgs, nil
+	}
+
+	var rest []*cover.Profile
+	for _, profile := range profiles {
+		if pkg, ok := pkgs[path.Dir(profile.FileName)]; ok && pkg == nil {
+			rest = append(rest, profile)
+		}
+	}
+	if len(rest) == 0 {
+		return pkgs, nil
+	}
+	listed, err := findPkgs(rest, r.goList)
+	if err != nil {
+		// Packages that can't be listed are reported by FindFile, along with the error.
+		r.listErr = err
+		return pkgs, nil
+	}
+	for importPath, pkg := range listed {
+		if pkg != nil {
+			pkgs[importPath] = pkg
+		}
+	}
+	return pkgs, nil
+}
+
+// hasGoListOptions reports whether any option of go list is set.
+func (r *Resolver) hasGoListOptions() bool {
+	return r.Go != "" || r.Tags != "" || r.GoFlags != "" || r.GOOS != "" || r.GOARCH != ""
+}
+
+// goList returns the go list command with the arguments, configured by the resolver.
+func (r *Resolver) goList(args ...string) *exec.Cmd {
+	name := r.Go
+	if name == "" {
+		name = goTool()
+	}
+	if r.Tags != "" {
+		args = append([]string{"-tags", r.Tags}, args...)
+	}
+	cmd := exec.Command(name, append([]string{"list"}, args...)...)
+	cmd.Dir = r.Dir
+	for _, env := range [][2]string{{"GOFLAGS", r.GoFlags}, {"GOOS", r.GOOS}, {"GOARCH", r.GOARCH}} {
+		if env[1] != "" {
+			cmd.Env = append(cmd.Environ(), env[0]+"="+env[1])
+		}
+	}
+	return cmd
+}
+
+// FindFile finds the location of the named file of a profile in the packages, rewritten by the source map.
+// A file whose name matches the source map is not looked up in the packages,
+// so the source map can also map import paths to directories.
+func (r *Resolver) FindFile(pkgs map[string]*Pkg, file string) (string, error) {
+	filename, ok := r.mapSource(file)
+	if !ok {
+		found, err := findFile(pkgs, file)
+		if err != nil {
+			if r.listErr != nil {
+				return "", fmt.Errorf("%v: %v", err, r.listErr)
+			}
+			return "", err
+		}
+		filename, _ = r.mapSource(found)
+	}
+	if r.Dir != "" && !filepath.IsAbs(filename) {
+		filename = filepath.Join(r.Dir, filename)
+	}
+	return filename, nil
+}
+
+// mapSource rewrites the path with the longest matching prefix of the source map.
+// It reports whether any prefix matched.
+func (r *Resolver) mapSource(name string) (string, bool) {
+	var found *config.SourceMapping
+	for _, mapping := range r.SourceMap {
+		if !hasPathPrefix(name, mapping.Old) {
+			continue
+		}
+		if found == nil || len(mapping.Old) > len(found.Old) {
+			found = mapping
+		}
+	}
+	if found == nil {
+		return name, false
+	}
+	rest := strings.TrimLeft(name[len(trimPathSeparators(found.Old)):], pathSeparators)
+	if rest == "" {
+		return found.New, true
+	}
+	return filepath.Join(found.New, filepath.FromSlash(rest)), true
+}
+
+// hasPathPrefix reports whether the path is the prefix or within the prefix directory.
+func hasPathPrefix(name string, prefix string) bool {
+	prefix = trimPathSeparators(prefix)
+	if prefix == "" {
+		// The root directory.
+		return strings.ContainsAny(name[:min(len(name), 1)], pathSeparators)
+	}
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return len(name) == len(prefix) || name[len(prefix)] == '/' || name[len(prefix)] == filepath.Separator
+}
+
+// pathSeparators are the separators of slash-separated and OS-specific paths.
+const pathSeparators = "/" + string(filepath.Separator)
+
+// trimPathSeparators returns the path without trailing separators.
+func trimPathSeparators(name string) string {
+	return strings.TrimRight(name, pathSeparators)
+}
+
+// findModPkgs finds the location of every package of the profiles within the modules.
+// Packages outside of the modules or without a directory are left nil.
+func findModPkgs(profiles []*cover.Profile, modules []*Module) map[string]*Pkg {
+	pkgs := make(map[string]*Pkg)
+	for _, profile := range profiles {
+		if strings.HasPrefix(profile.FileName, ".") || filepath.IsAbs(profile.FileName) {
+			// Ignore relative or absolute path.
+			continue
+		}
+		importPath := path.Dir(profile.FileName)
+		if _, ok := pkgs[importPath]; ok {
+			continue
+		}
+		pkgs[importPath] = nil
+
+		module := findModule(modules, importPath)
+		if module == nil {
+			continue
+		}
+		dir := filepath.Join(module.Dir, filepath.FromSlash(strings.TrimPrefix(importPath, module.Path)))
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			pkgs[importPath] = &Pkg{ImportPath: importPath, Dir: dir, Module: module}
+		}
+	}
+	return pkgs
+}
+
+// findModule returns the module with the longest path containing the import path, or nil if there is none.
+func findModule(modules []*Module, importPath string) *Module {
+	var found *Module
+	for _, module := range modules {
+		if importPath != module.Path && !strings.HasPrefix(importPath, module.Path+"/") {
+			continue
+		}
+		if found == nil || len(module.Path) > len(found.Path) {
+			found = module
+		}
+	}
+	return found
+}
+
+// FindModules returns the main modules of the workspace or module containing the directory.
+// Like the go command, a go.work file in the directory or its parents takes precedence over go.mod files.
+// It returns no module when there is neither.
+func FindModules(dir string) ([]*Module, error) {
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var gomod string
+	for d := dir; ; d = filepath.Dir(d) {
+		if gowork := filepath.Join(d, "go.work"); isFile(gowork) {
+			return readWorkModules(gowork)
+		}
+		if candidate := filepath.Join(d, "go.mod"); gomod == "" && isFile(candidate) {
+			gomod = candidate
+		}
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+	if gomod == "" {
+		return nil, nil
+	}
+
+	module, err := readModule(gomod)
+	if err != nil {
+		return nil, err
+	}
+	return []*Module{module}, nil
+}
+
+// isFile reports whether the named file exists and is a regular file.
+func isFile(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// readWorkModules returns the modules used by the go.work file.
+func readWorkModules(gowork string) ([]*Module, error) {
+	dirs, err := readDirectives(gowork, "use")
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*Module, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(gowork), dir)
+		}
+		module, err := readModule(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
+
+// readModule returns the module declared by the go.mod file.
+func readModule(gomod string) (*Module, error) {
+	paths, err := readDirectives(gomod, "module")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no module directive in %q", gomod)
+	}
+	return &Module{Path: paths[0], Dir: filepath.Dir(gomod), Main: true}, nil
+}
+
+// readDirectives returns the arguments of the named directives of a go.mod or go.work file,
+// in both the single line and the parenthesized block forms.
+func readDirectives(filename string, verb string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	defer file.Close()
+
+	var args []string
+	inBlock := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inBlock && fields[0] == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case fields[0] != verb || len(fields) < 2:
+			continue
+		case fields[1] == "(":
+			inBlock = true
+			continue
+		default:
+			fields = fields[1:]
+		}
+
+		arg, err := unquoteModArg(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %q: %v", filename, err)
+		}
+		args = append(args, arg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read %q: %v", filename, err)
+	}
+	return args, nil
+}
+
+// unquoteModArg unquotes an argument of a go.mod or go.work directive if it is quoted.
+func unquoteModArg(arg string) (string, error) {
+	if !strings.HasPrefix(arg, `"`) && !strings.HasPrefix(arg, "`") {
+		return arg, nil
+	}
+	unquoted, err := strconv.Unquote(arg)
+	if err != nil {
+		return "", errors.New("invalid quoted string " + arg)
+	}
+	return unquoted, nil
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+// writeFiles writes the files of the contents keyed by slash-separated names under the directory.
+func writeFiles(t *testing.T, dir string, contents map[string]string) {
+	for name, content := range contents {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		assert.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+	}
+}
+
+func TestFindModules(t *testing.T) {
+	t.Run("should find module of go.mod in parent directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"go.mod":     "// comment\nmodule \"example.com/foo\" // trailing\n\ngo 1.21\n",
+			"bar/bar.go": "package bar\n",
+		})
+
+		modules, err := FindModules(filepath.Join(dir, "bar"))
+		assert.NoError(t, err)
+		assert.Equal(t, []*Module{{Path: "example.com/foo", Dir: dir, Main: true}}, modules)
+	})
+
+	t.Run("should prefer go.work to go.mod", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"go.work":   "go 1.21\n\nuse ./a\nuse (\n\t./b // comment\n)\n",
+			"a/go.mod":  "module example.com/a\n",
+			"b/go.mod":  "module example.com/b\n",
+			"b/c/c.go":  "package c\n",
+			"unused.go": "package unused\n",
+		})
+
+		modules, err := FindModules(filepath.Join(dir, "b", "c"))
+		assert.NoError(t, err)
+		assert.Equal(t, []*Module{
+			{Path: "example.com/a", Dir: filepath.Join(dir, "a"), Main: true},
+			{Path: "example.com/b", Dir: filepath.Join(dir, "b"), Main: true},
+		}, modules)
+	})
+
+	t.Run("should return error when go.mod has no module directive", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"go.mod": "go 1.21\n"})
+
+		_, err := FindModules(dir)
+		assert.ErrorContains(t, err, "no module directive")
+	})
+}
+
+func TestFindModule(t *testing.T) {
+	modules := []*Module{{Path: "example.com/a"}, {Path: "example.com/a/b"}}
+	assert.Equal(t, modules[0], findModule(modules, "example.com/a"))
+	assert.Equal(t, modules[0], findModule(modules, "example.com/a/c"))
+	assert.Equal(t, modules[1], findModule(modules, "example.com/a/b/c"))
+	assert.Nil(t, findModule(modules, "example.com/ab"))
+}
+
+func TestResolverFindPkgs(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
+	curDir := filepath.Dir(curFilename)
+	curPkg := "github.com/cancue/covreport/reporter/internal"
+	depPkg := "github.com/stretchr/testify/assert"
+	profiles := []*cover.Profile{
+		{FileName: curPkg + "/resolve.go"},
+		{FileName: depPkg + "/assertions.go"},
+		{FileName: "example.com/not-exist/foo.go"},
+	}
+
+	t.Run("should resolve packages of main modules without go list", func(t *testing.T) {
+		pkgs, err := (&Resolver{Strategy: ResolveMod}).FindPkgs(profiles)
+		assert.NoError(t, err)
+		assert.Equal(t, curDir, pkgs[curPkg].Dir)
+		assert.Equal(t, "github.com/cancue/covreport", pkgs[curPkg].Module.Path)
+		assert.Nil(t, pkgs[depPkg])
+	})
+
+	t.Run("should fall back to go list for packages outside of main modules", func(t *testing.T) {
+		pkgs, err := (&Resolver{}).FindPkgs(profiles)
+		assert.NoError(t, err)
+		assert.Equal(t, curDir, pkgs[curPkg].Dir)
+		assert.NotEmpty(t, pkgs[depPkg].Dir)
+		assert.Nil(t, pkgs["example.com/not-exist"].Module)
+	})
+
+	t.Run("should report go list error with packages not found", func(t *testing.T) {
+		t.Setenv("GOFLAGS", "-bogusflag")
+		r := &Resolver{}
+		pkgs, err := r.FindPkgs(profiles)
+		assert.NoError(t, err)
+		assert.Equal(t, curDir, pkgs[curPkg].Dir)
+
+		_, err = r.FindFile(pkgs, depPkg+"/assertions.go")
+		assert.ErrorContains(t, err, "did not find package for "+depPkg+"/assertions.go")
+		assert.ErrorContains(t, err, "unknown flag -bogusflag")
+	})
+
+	t.Run("should resolve packages with go list only", func(t *testing.T) {
+		pkgs, err := (&Resolver{Strategy: ResolveGoList}).FindPkgs(profiles[:1])
+		assert.NoError(t, err)
+		assert.Equal(t, curDir, pkgs[curPkg].Dir)
+	})
+
+	t.Run("should list main module packages with go list options", func(t *testing.T) {
+		r := &Resolver{GoFlags: "-bogusflag"}
+		_, err := r.FindPkgs(profiles[:1])
+		assert.ErrorContains(t, err, "unknown flag -bogusflag")
+
+		r = &Resolver{Tags: "integration"}
+		pkgs, err := r.FindPkgs(profiles[:1])
+		assert.NoError(t, err)
+		assert.Equal(t, curDir, pkgs[curPkg].Dir)
+	})
+
+	t.Run("should return error when go list options are set without go list", func(t *testing.T) {
+		_, err := (&Resolver{Strategy: ResolveMod, Tags: "integration"}).FindPkgs(profiles)
+		assert.ErrorContains(t, err, `resolver "mod" can't be used with the go command, tags`)
+	})
+
+	t.Run("should return error when strategy is unknown", func(t *testing.T) {
+		_, err := (&Resolver{Strategy: "magic"}).FindPkgs(profiles)
+		assert.ErrorContains(t, err, `unknown resolver "magic"`)
+	})
+}
+
+func TestResolverFindFile(t *testing.T) {
+	pkgs := map[string]*Pkg{
+		"example.com/foo/bar": {ImportPath: "example.com/foo/bar", Dir: "/workspace/foo/bar"},
+	}
+
+	t.Run("should rewrite resolved path with longest matching prefix", func(t *testing.T) {
+		r := &Resolver{SourceMap: []*config.SourceMapping{
+			{Old: "/workspace", New: "/home/me"},
+			{Old: "/workspace/foo/", New: "/home/me/repo"},
+			{Old: "/work", New: "/wrong"},
+		}}
+		filename, err := r.FindFile(pkgs, "example.com/foo/bar/bar.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/home/me/repo/bar/bar.go"), filename)
+
+		filename, err = r.FindFile(pkgs, "/workspace/baz.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/home/me/baz.go"), filename)
+	})
+
+	t.Run("should map import paths without packages", func(t *testing.T) {
+		r := &Resolver{SourceMap: []*config.SourceMapping{{Old: "example.com/qux", New: "/src/qux"}}}
+		filename, err := r.FindFile(pkgs, "example.com/qux/pkg/qux.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/src/qux/pkg/qux.go"), filename)
+
+		_, err = r.FindFile(pkgs, "example.com/quxx/quxx.go")
+		assert.ErrorContains(t, err, "did not find package for example.com/quxx/quxx.go")
+	})
+
+	t.Run("should read relative paths from source root", func(t *testing.T) {
+		r := &Resolver{Dir: "/src", SourceMap: []*config.SourceMapping{{Old: "example.com/qux", New: "qux"}}}
+		filename, err := r.FindFile(pkgs, "./foo.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/src/foo.go"), filename)
+
+		filename, err = r.FindFile(pkgs, "example.com/qux/qux.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/src/qux/qux.go"), filename)
+
+		filename, err = r.FindFile(pkgs, "example.com/foo/bar/bar.go")
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.FromSlash("/workspace/foo/bar/bar.go"), filename)
+	})
+}
+
+func TestPkgIsLocalDep(t *testing.T) {
+	t.Run("should tell replaced and vendored dependencies", func(t *testing.T) {
+		tests := []struct {
+			pkg  *Pkg
+			want bool
+		}{
+			{nil, false},
+			{&Pkg{Dir: "/m/foo"}, false},
+			{&Pkg{Dir: "/m/foo", Module: &Module{Path: "example.com/m", Main: true}}, false},
+			{&Pkg{Dir: "/go/pkg/mod/example.com/dep@v1.0.0", Module: &Module{Path: "example.com/dep", Version: "v1.0.0"}}, false},
+			{&Pkg{Dir: "/go/pkg/mod/example.com/fork@v1.0.0", Module: &Module{Path: "example.com/dep", Replace: &Module{Path: "example.com/fork", Version: "v1.0.0"}}}, false},
+			{&Pkg{Dir: "/dep/bar", Module: &Module{Path: "example.com/dep", Replace: &Module{Path: "../dep"}}}, true},
+			{&Pkg{Dir: "/m/vendor/example.com/v/baz", Module: &Module{Path: "example.com/v", Version: "v1.0.0"}}, true},
+		}
+		for _, tc := range tests {
+			assert.Equal(t, tc.want, tc.pkg.IsLocalDep())
+		}
+	})
+}
+
+func TestResolverGoList(t *testing.T) {
+	t.Run("should pass go command, tags and environment", func(t *testing.T) {
+		r := &Resolver{Dir: "/src", Go: "/opt/go/bin/go", Tags: "integration,e2e", GoFlags: "-mod=vendor", GOOS: "windows"}
+		cmd := r.goList("-e", "-json")
+		assert.Equal(t, []string{"/opt/go/bin/go", "list", "-tags", "integration,e2e", "-e", "-json"}, cmd.Args)
+		assert.Equal(t, "/src", cmd.Dir)
+		assert.Contains(t, cmd.Env, "GOFLAGS=-mod=vendor")
+		assert.Contains(t, cmd.Env, "GOOS=windows")
+		assert.NotContains(t, cmd.Env, "GOARCH=")
+	})
+
+	t.Run("should use environment as is by default", func(t *testing.T) {
+		cmd := (&Resolver{}).goList("-e")
+		assert.Equal(t, []string{"list", "-e"}, cmd.Args[1:])
+		assert.Nil(t, cmd.Env)
+	})
+}
@@ -0,0 +1,271 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+const (
+	// SARIFBlocks reports every uncovered block.
+	SARIFBlocks = "blocks"
+	// SARIFFunctions reports every function none of whose statements are covered.
+	SARIFFunctions = "functions"
+)
+
+// SARIFResultModes are the names of what SARIF results report.
+var SARIFResultModes = []string{SARIFBlocks, SARIFFunctions}
+
+// sarifRules are the rules of the results, keyed by the result modes.
+var sarifRules = map[string]*SARIFRule{
+	SARIFBlocks: {
+		ID:               "uncovered-block",
+		ShortDescription: &SARIFMessage{Text: "Block of statements not covered by tests"},
+	},
+	SARIFFunctions: {
+		ID:               "uncovered-function",
+		ShortDescription: &SARIFMessage{Text: "Function not covered by tests"},
+	},
+}
+
+// sarifLevels are the levels of results of the coverage class names of their files.
+var sarifLevels = map[string]string{
+	"safe":    "note",
+	"warning": "warning",
+	"danger":  "error",
+	"":        "warning",
+}
+
+// SARIFRenderer renders the uncovered code of a GoProject as a SARIF 2.1.0 log for code scanning.
+// The level of each result is mapped from the coverage class of its file.
+type SARIFRenderer struct {
+	// RepoRoot is the absolute path of the repository the locations are relative to.
+	RepoRoot string
+	// Results is one of SARIFResultModes. SARIFBlocks is used when empty.
+	Results string
+}
+
+// NewSARIFRenderer returns a new SARIFRenderer for the given configuration.
+func NewSARIFRenderer(cfg *config.Config) (Renderer, error) {
+	if cfg.SARIFResults != "" && !slices.Contains(SARIFResultModes, cfg.SARIFResults) {
+		return nil, fmt.Errorf("unknown sarif results %q, expected one of %s", cfg.SARIFResults, strings.Join(SARIFResultModes, ", "))
+	}
+	root, _, err := newRepoOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &SARIFRenderer{RepoRoot: root, Results: cfg.SARIFResults}, nil
+}
+
+// SARIFLog is the root of a SARIF log; see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+type SARIFLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*SARIFRun `json:"runs"`
+}
+
+// SARIFRun is a run of a tool.
+type SARIFRun struct {
+	Tool    *SARIFTool     `json:"tool"`
+	Results []*SARIFResult `json:"results"`
+}
+
+// SARIFTool describes the tool and its rules.
+type SARIFTool struct {
+	Driver *SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the component of the tool with the rules.
+type SARIFDriver struct {
+	Name           string       `json:"name"`
+	InformationURI string       `json:"informationUri"`
+	Rules          []*SARIFRule `json:"rules"`
+}
+
+// SARIFRule describes a rule the results are reported for.
+type SARIFRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFMessage is a plain text message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is a result of a rule.
+type SARIFResult struct {
+	RuleID    string           `json:"ruleId"`
+	Level     string           `json:"level"`
+	Message   *SARIFMessage    `json:"message"`
+	Locations []*SARIFLocation `json:"locations"`
+}
+
+// SARIFLocation is the location of a result.
+type SARIFLocation struct {
+	PhysicalLocation *SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is a region of a file.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation *SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion           `json:"region"`
+}
+
+// SARIFArtifactLocation is the location of a file, relative to the repository root.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SARIFRegion is a range of a file, whose lines and columns start at 1.
+type SARIFRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// Render writes the SARIF log of the uncovered code of the files in the repository to the provided io.Writer.
+func (r *SARIFRenderer) Render(gp *GoProject, wr io.Writer) error {
+	mode := r.Results
+	if mode == "" {
+		mode = SARIFBlocks
+	}
+	rule := sarifRules[mode]
+
+	results := []*SARIFResult{}
+	for _, file := range gp.InitialDir().AllFiles() {
+		path, ok := RepoPath(r.RepoRoot, file)
+		if !ok {
+			continue
+		}
+		level := sarifLevels[""]
+		if gp.Cutlines != nil {
+			level = sarifLevels[coverageClassName(file.GoListItem, gp.Cutlines)]
+		}
+
+		var regions []*SARIFRegion
+		var messages []string
+		if mode == SARIFFunctions {
+			funcs, err := uncoveredFuncs(file)
+			if err != nil {
+				return err
+			}
+			for _, fn := range funcs {
+				regions = append(regions, fn.Region)
+				messages = append(messages, fmt.Sprintf("Function %s is not covered by tests", fn.Name))
+			}
+		} else {
+			for _, block := range file.Profile {
+				if block.Count > 0 {
+					continue
+				}
+				regions = append(regions, &SARIFRegion{StartLine: block.StartLine, StartColumn: block.StartCol, EndLine: block.EndLine, EndColumn: block.EndCol})
+				messages = append(messages, fmt.Sprintf("%d statements are not covered by tests", block.NumStmt))
+			}
+		}
+
+		for i, region := range regions {
+			results = append(results, &SARIFResult{
+				RuleID:  rule.ID,
+				Level:   level,
+				Message: &SARIFMessage{Text: messages[i]},
+				Locations: []*SARIFLocation{{PhysicalLocation: &SARIFPhysicalLocation{
+					ArtifactLocation: &SARIFArtifactLocation{URI: path},
+					Region:           region,
+				}}},
+			})
+		}
+	}
+
+	enc := json.NewEncoder(wr)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&SARIFLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []*SARIFRun{{
+			Tool: &SARIFTool{Driver: &SARIFDriver{
+				Name:           "covreport",
+				InformationURI: "https://github.com/cancue/covreport",
+				Rules:          []*SARIFRule{rule},
+			}},
+			Results: results,
+		}},
+	})
+}
+
+// sarifFunc is a function of a file with its region.
+type sarifFunc struct {
+	Name   string
+	Region *SARIFRegion
+}
+
+// uncoveredFuncs parses the source of the file and returns the functions with statements none of which are covered.
+// Like 'go tool cover -func', the statements of a function are those of the profile blocks within it.
+func uncoveredFuncs(file *GoFile) ([]*sarifFunc, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, file.ABSPath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse %q: %v", file.RelPkgPath, err)
+	}
+
+	var funcs []*sarifFunc
+	for _, decl := range parsed.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		start, end := fset.Position(fn.Pos()), fset.Position(fn.End())
+		var stmts, covered int
+		for _, block := range file.Profile {
+			if block.StartLine < start.Line || block.StartLine == start.Line && block.StartCol < start.Column {
+				continue
+			}
+			if block.EndLine > end.Line || block.EndLine == end.Line && block.EndCol > end.Column {
+				continue
+			}
+			stmts += block.NumStmt
+			if block.Count > 0 {
+				covered += block.NumStmt
+			}
+		}
+		if stmts > 0 && covered == 0 {
+			funcs = append(funcs, &sarifFunc{
+				Name:   funcName(fn),
+				Region: &SARIFRegion{StartLine: start.Line, StartColumn: start.Column, EndLine: end.Line, EndColumn: end.Column},
+			})
+		}
+	}
+	return funcs, nil
+}
+
+// funcName returns the name of the function, qualified by the type of its receiver if it is a method.
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	typ := fn.Recv.List[0].Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+			continue
+		case *ast.IndexExpr:
+			typ = t.X
+			continue
+		case *ast.IndexListExpr:
+			typ = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + fn.Name.Name
+		}
+		return fn.Name.Name
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+func TestSARIFRender(t *testing.T) {
+	root := t.TempDir()
+	src := "package a\n" + // 1
+		"\n" + // 2
+		"func Covered() int {\n" + // 3
+		"\treturn 1\n" + // 4
+		"}\n" + // 5
+		"\n" + // 6
+		"type T[K any] struct{}\n" + // 7
+		"\n" + // 8
+		"func (t *T[K]) Uncovered(ok bool) int {\n" + // 9
+		"\tif ok {\n" + // 10
+		"\t\treturn 1\n" + // 11
+		"\t}\n" + // 12
+		"\treturn 0\n" + // 13
+		"}\n" // 14
+	writeFiles(t, root, map[string]string{"a/a.go": src})
+
+	gp := NewGoProject("example.com/a", &config.Cutlines{Safe: 70, Warning: 40})
+	file := &GoFile{
+		GoListItem: NewGoListItem("example.com/a/a.go"),
+		ABSPath:    filepath.Join(root, "a", "a.go"),
+		Profile: []cover.ProfileBlock{
+			{StartLine: 3, StartCol: 20, EndLine: 5, EndCol: 2, NumStmt: 1, Count: 1},
+			{StartLine: 9, StartCol: 40, EndLine: 10, EndCol: 8, NumStmt: 1, Count: 0},
+			{StartLine: 10, StartCol: 8, EndLine: 12, EndCol: 3, NumStmt: 1, Count: 0},
+			{StartLine: 13, StartCol: 2, EndLine: 13, EndCol: 10, NumStmt: 1, Count: 0},
+		},
+	}
+	file.StmtCount, file.StmtCoveredCount = 4, 1
+	gp.Root().AddFile(file)
+	gp.Root().Aggregate()
+
+	render := func(t *testing.T, r *SARIFRenderer) *SARIFLog {
+		var buf strings.Builder
+		assert.NoError(t, r.Render(gp, &buf))
+		var log SARIFLog
+		assert.NoError(t, json.Unmarshal([]byte(buf.String()), &log))
+		assert.Equal(t, "2.1.0", log.Version)
+		assert.Len(t, log.Runs, 1)
+		return &log
+	}
+
+	t.Run("should report uncovered blocks", func(t *testing.T) {
+		log := render(t, &SARIFRenderer{RepoRoot: root})
+		run := log.Runs[0]
+		assert.Equal(t, "uncovered-block", run.Tool.Driver.Rules[0].ID)
+		assert.Len(t, run.Results, 3)
+
+		result := run.Results[0]
+		assert.Equal(t, "uncovered-block", result.RuleID)
+		assert.Equal(t, "error", result.Level)
+		assert.Equal(t, "1 statements are not covered by tests", result.Message.Text)
+		location := result.Locations[0].PhysicalLocation
+		assert.Equal(t, "a/a.go", location.ArtifactLocation.URI)
+		assert.Equal(t, &SARIFRegion{StartLine: 9, StartColumn: 40, EndLine: 10, EndColumn: 8}, location.Region)
+	})
+
+	t.Run("should report uncovered functions", func(t *testing.T) {
+		log := render(t, &SARIFRenderer{RepoRoot: root, Results: SARIFFunctions})
+		run := log.Runs[0]
+		assert.Equal(t, "uncovered-function", run.Tool.Driver.Rules[0].ID)
+		assert.Len(t, run.Results, 1)
+
+		result := run.Results[0]
+		assert.Equal(t, "Function T.Uncovered is not covered by tests", result.Message.Text)
+		assert.Equal(t, &SARIFRegion{StartLine: 9, StartColumn: 1, EndLine: 14, EndColumn: 2}, result.Locations[0].PhysicalLocation.Region)
+	})
+
+	t.Run("should have empty results outside of repository", func(t *testing.T) {
+		log := render(t, &SARIFRenderer{RepoRoot: filepath.Join(root, "b")})
+		assert.NotNil(t, log.Runs[0].Results)
+		assert.Empty(t, log.Runs[0].Results)
+	})
+}
+
+func TestNewSARIFRenderer(t *testing.T) {
+	t.Run("should return error when results mode is unknown", func(t *testing.T) {
+		_, err := NewSARIFRenderer(&config.Config{SARIFResults: "lines"})
+		assert.ErrorContains(t, err, `unknown sarif results "lines"`)
+	})
+}
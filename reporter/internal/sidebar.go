@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// TemplateTreeNodeData represents a directory or file node of the sidebar tree.
+type TemplateTreeNodeData struct {
+	ID        string
+	Title     string
+	ClassName string
+	Percent   string
+	IsDir     bool
+	Children  []*TemplateTreeNodeData
+}
+
+// NewTemplateTreeNodeData recursively converts the directory to a sidebar tree node with the given title.
+func NewTemplateTreeNodeData(dir *GoDir, title string, cutlines *config.Cutlines) *TemplateTreeNodeData {
+	node := &TemplateTreeNodeData{
+		ID:        dir.ID,
+		Title:     title,
+		ClassName: coverageClassName(dir.GoListItem, cutlines),
+		Percent:   fmt.Sprintf("%.1f%%", dir.Percent()),
+		IsDir:     true,
+		Children:  make([]*TemplateTreeNodeData, 0, len(dir.SubDirs)+len(dir.Files)),
+	}
+	for _, subDir := range dir.SubDirs {
+		node.Children = append(node.Children, NewTemplateTreeNodeData(subDir, subDir.Title, cutlines))
+	}
+	for _, file := range dir.Files {
+		node.Children = append(node.Children, &TemplateTreeNodeData{
+			ID:        file.ID,
+			Title:     file.Title,
+			ClassName: coverageClassName(file.GoListItem, cutlines),
+			Percent:   fmt.Sprintf("%.1f%%", file.Percent()),
+		})
+	}
+	return node
+}
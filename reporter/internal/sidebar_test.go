@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTemplateTreeNodeData(t *testing.T) {
+	cutlines := &config.Cutlines{Safe: 70, Warning: 40}
+	gp := NewGoProject("a", cutlines)
+	file := &GoFile{GoListItem: NewGoListItem("a/b/c.go")}
+	file.StmtCount = 10
+	file.StmtCoveredCount = 5
+	gp.SafeDir("a/b").AddFile(file)
+	gp.Root().Aggregate()
+
+	root := NewTemplateTreeNodeData(gp.Root(), "root", cutlines)
+	assert.Equal(t, gp.Root().ID, root.ID)
+	assert.Equal(t, "root", root.Title)
+	assert.True(t, root.IsDir)
+	assert.Len(t, root.Children, 1)
+
+	b := root.Children[0]
+	assert.Equal(t, "b", b.Title)
+	assert.True(t, b.IsDir)
+	assert.Equal(t, "warning", b.ClassName)
+	assert.Len(t, b.Children, 1)
+
+	c := b.Children[0]
+	assert.Equal(t, file.ID, c.ID)
+	assert.Equal(t, "c.go", c.Title)
+	assert.Equal(t, "50.0%", c.Percent)
+	assert.False(t, c.IsDir)
+	assert.Empty(t, c.Children)
+}
+
+func TestHTMLRenderSidebar(t *testing.T) {
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	gp.SafeDir("a/b")
+
+	var buf strings.Builder
+	assert.NoError(t, (&HTMLRenderer{}).Render(gp, &buf))
+	assert.NotContains(t, buf.String(), `<nav class="sidebar">`)
+
+	buf.Reset()
+	assert.NoError(t, (&HTMLRenderer{Sidebar: true}).Render(gp, &buf))
+	assert.Contains(t, buf.String(), `<body class="palette-default has-sidebar">`)
+	assert.Contains(t, buf.String(), `<nav class="sidebar">`)
+	assert.Contains(t, buf.String(), `<details data-id="`+gp.SafeDir("a/b").ID+`">`)
+}
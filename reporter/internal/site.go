@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// SiteRenderer renders a GoProject as a static site with a page per directory and file.
+// Each page is named after the ID of its view, and index.html is the page of the initial directory.
+// The styles, the script and the sidebar tree are written once as style.css, script.js and tree.js.
+// Pages of views left in the directory by a previous site are removed, while other files are kept.
+type SiteRenderer struct {
+	HTML *HTMLRenderer
+}
+
+// NewSiteRenderer returns a new SiteRenderer for the given configuration.
+func NewSiteRenderer(cfg *config.Config) (Renderer, error) {
+	html, err := NewHTMLRenderer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &SiteRenderer{HTML: html.(*HTMLRenderer)}, nil
+}
+
+// Render returns an error because a site can't be written to a single io.Writer.
+func (r *SiteRenderer) Render(gp *GoProject, wr io.Writer) error {
+	return errors.New("site format can only be written to a directory")
+}
+
+// RenderDir writes the pages of the GoProject to the directory, creating it if needed.
+func (r *SiteRenderer) RenderDir(gp *GoProject, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("can't create %q: %v", dir, err)
+	}
+	if err := removeViewPages(dir); err != nil {
+		return err
+	}
+
+	tmpl, err := r.HTML.template().Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{"href": viewPage})
+
+	data := r.HTML.NewTemplateData(gp)
+	data.Assets = true
+	if err := writeAssets(tmpl, data, dir); err != nil {
+		return err
+	}
+
+	wait := data.StreamViews(gp.InitialDir(), r.HTML.jobs())
+	for view := range data.ViewStream {
+		if err = writePage(tmpl, data, view, filepath.Join(dir, viewPage(view.ID))); err != nil {
+			break
+		}
+		if view.ID == data.InitialID {
+			if err = writePage(tmpl, data, view, filepath.Join(dir, "index.html")); err != nil {
+				break
+			}
+		}
+	}
+	if walkErr := wait(); walkErr != nil {
+		return walkErr
+	}
+	return err
+}
+
+// viewPageName matches the names of the pages of views, whose IDs are UUIDs.
+var viewPageName = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\.html$`)
+
+// removeViewPages removes the pages of views in the directory.
+func removeViewPages(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("can't read %q: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && viewPageName.MatchString(entry.Name()) {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return fmt.Errorf("can't remove %q: %v", filepath.Join(dir, entry.Name()), err)
+			}
+		}
+	}
+	return nil
+}
+
+// viewPage returns the link to the page of the view with the given ID within a site.
+func viewPage(id string) string {
+	return id + ".html"
+}
+
+// writeAssets writes the files shared by the pages of the site.
+// The sidebar tree is a script inserting the tree before itself, so that it doesn't have to be fetched.
+func writeAssets(tmpl *template.Template, data *TemplateData, dir string) error {
+	var style strings.Builder
+	if err := tmpl.ExecuteTemplate(&style, "style", data); err != nil {
+		return err
+	}
+	if data.CSS != "" {
+		style.WriteString("\n" + data.CSS + "\n")
+	}
+	if err := writeAsset(filepath.Join(dir, "style.css"), style.String()); err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	if err := tmpl.ExecuteTemplate(&script, "script", data); err != nil {
+		return err
+	}
+	if err := writeAsset(filepath.Join(dir, "script.js"), script.String()); err != nil {
+		return err
+	}
+
+	if data.Tree == nil {
+		return nil
+	}
+	var tree strings.Builder
+	tree.WriteString("<ul>")
+	if err := tmpl.ExecuteTemplate(&tree, "tree", data.Tree); err != nil {
+		return err
+	}
+	tree.WriteString("</ul>")
+	html, err := json.Marshal(tree.String())
+	if err != nil {
+		return err
+	}
+	return writeAsset(filepath.Join(dir, "tree.js"), fmt.Sprintf("document.currentScript.insertAdjacentHTML('beforebegin', %s);\n", html))
+}
+
+// writeAsset writes the content of the named asset.
+func writeAsset(filename string, content string) error {
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("can't create %q: %v", filename, err)
+	}
+	return nil
+}
+
+// writePage writes the named page showing only the given view.
+func writePage(tmpl *template.Template, data *TemplateData, view *TemplateViewData, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("can't create %q: %v", filename, err)
+	}
+
+	page := *data
+	page.Views = []*TemplateViewData{view}
+	page.ViewStream = sendViews(page.Views)
+	page.InitialID = view.ID
+	if err := tmpl.Execute(file, &page); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("can't write %q: %v", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSiteRenderDir(t *testing.T) {
+	_, curFilename, _, ok := runtime.Caller(0)
+	assert.True(t, ok)
+
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	file := &GoFile{ABSPath: curFilename, GoListItem: NewGoListItem("a/b/site_test.go")}
+	b := gp.SafeDir("a/b")
+	b.AddFile(file)
+
+	r, err := NewSiteRenderer(&config.Config{Sidebar: true})
+	assert.NoError(t, err)
+
+	t.Run("should return error when rendering to a writer", func(t *testing.T) {
+		err := r.Render(gp, &strings.Builder{})
+		assert.ErrorContains(t, err, "can only be written to a directory")
+	})
+
+	t.Run("should write index and a page per view", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "site")
+		err := r.(DirRenderer).RenderDir(gp, dir)
+		assert.NoError(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.ElementsMatch(t, []string{"index.html", gp.Root().ID + ".html", b.ID + ".html", file.ID + ".html", "style.css", "script.js", "tree.js"}, names)
+
+		index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+		assert.NoError(t, err)
+		page, err := os.ReadFile(filepath.Join(dir, gp.Root().ID+".html"))
+		assert.NoError(t, err)
+		assert.Equal(t, string(page), string(index))
+		assert.Contains(t, string(index), `href="`+b.ID+`.html"`)
+		assert.NotContains(t, string(index), `<div id="`+b.ID+`"`)
+		assert.Contains(t, string(index), `<link rel="stylesheet" href="style.css">`)
+		assert.Contains(t, string(index), `<script src="tree.js"></script>`)
+		assert.NotContains(t, string(index), "font-family: Menlo, monospace;")
+
+		filePage, err := os.ReadFile(filepath.Join(dir, file.ID+".html"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(filePage), `data-initial-id="`+file.ID+`"`)
+		assert.Contains(t, string(filePage), `<a class="line-number" href="#`+file.ID+`:L1">1</a>`)
+
+		style, err := os.ReadFile(filepath.Join(dir, "style.css"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(style), "font-family: Menlo, monospace;")
+		script, err := os.ReadFile(filepath.Join(dir, "script.js"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(script), "const initialID = document.body.dataset.initialId;")
+		tree, err := os.ReadFile(filepath.Join(dir, "tree.js"))
+		assert.NoError(t, err)
+		assert.Contains(t, string(tree), "document.currentScript.insertAdjacentHTML('beforebegin', \"\\u003cul\\u003e")
+		assert.Contains(t, string(tree), `data-id=\"`+b.ID+`\"`)
+	})
+
+	t.Run("should remove pages of a previous site only", func(t *testing.T) {
+		dir := t.TempDir()
+		stale := filepath.Join(dir, NewGoListItem("a/old.go").ID+".html")
+		assert.NoError(t, os.WriteFile(stale, []byte("stale"), 0o644))
+		notes := filepath.Join(dir, "notes.html")
+		assert.NoError(t, os.WriteFile(notes, []byte("notes"), 0o644))
+
+		assert.NoError(t, r.(DirRenderer).RenderDir(gp, dir))
+		assert.NoFileExists(t, stale)
+		assert.FileExists(t, notes)
+		assert.FileExists(t, filepath.Join(dir, file.ID+".html"))
+	})
+
+	t.Run("should not grow pages of files with the project", func(t *testing.T) {
+		pageSize := func(numFiles int) int {
+			gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+			gp.SafeDir("a/b").AddFile(file)
+			for i := 0; i < numFiles; i++ {
+				gp.SafeDir(fmt.Sprintf("a/c%d", i)).AddFile(&GoFile{
+					GoListItem: NewGoListItem(fmt.Sprintf("a/c%d/c.go", i)),
+					SourceErr:  errors.New("missing"),
+				})
+			}
+			gp.Root().Aggregate()
+
+			dir := filepath.Join(t.TempDir(), "site")
+			assert.NoError(t, r.(DirRenderer).RenderDir(gp, dir))
+			info, err := os.Stat(filepath.Join(dir, file.ID+".html"))
+			assert.NoError(t, err)
+			return int(info.Size())
+		}
+		assert.Equal(t, pageSize(1), pageSize(100))
+	})
+}
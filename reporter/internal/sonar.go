@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+// SonarRenderer renders the line coverage of a GoProject in the generic test coverage format of SonarQube;
+// see https://docs.sonarsource.com/sonarqube/latest/analyzing-source-code/test-coverage/generic-test-data/.
+type SonarRenderer struct {
+	// RepoRoot is the absolute path of the repository the file paths are relative to.
+	RepoRoot string
+}
+
+// NewSonarRenderer returns a new SonarRenderer for the given configuration.
+func NewSonarRenderer(cfg *config.Config) (Renderer, error) {
+	root, _, err := newRepoOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &SonarRenderer{RepoRoot: root}, nil
+}
+
+// SonarCoverage is the root element of a generic coverage report.
+type SonarCoverage struct {
+	XMLName xml.Name     `xml:"coverage"`
+	Version int          `xml:"version,attr"`
+	Files   []*SonarFile `xml:"file"`
+}
+
+// SonarFile is the coverage of the lines of a file, whose path is relative to the repository root.
+type SonarFile struct {
+	Path  string       `xml:"path,attr"`
+	Lines []*SonarLine `xml:"lineToCover"`
+}
+
+// SonarLine is the coverage of a line with statements. A line with code of more than one profile block has
+// a branch for each of them.
+type SonarLine struct {
+	LineNumber      int  `xml:"lineNumber,attr"`
+	Covered         bool `xml:"covered,attr"`
+	BranchesToCover int  `xml:"branchesToCover,attr,omitempty"`
+	CoveredBranches *int `xml:"coveredBranches,attr"`
+}
+
+// Render writes the generic coverage report of the files in the repository to the provided io.Writer.
+// Files outside of the repository are skipped.
+func (r *SonarRenderer) Render(gp *GoProject, wr io.Writer) error {
+	coverage := &SonarCoverage{Version: 1, Files: []*SonarFile{}}
+	for _, file := range gp.InitialDir().AllFiles() {
+		path, ok := RepoPath(r.RepoRoot, file)
+		if !ok {
+			continue
+		}
+		coverage.Files = append(coverage.Files, &SonarFile{Path: path, Lines: sonarLines(file)})
+	}
+
+	if _, err := io.WriteString(wr, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(wr)
+	enc.Indent("", "  ")
+	if err := enc.Encode(coverage); err != nil {
+		return err
+	}
+	_, err := io.WriteString(wr, "\n")
+	return err
+}
+
+// sonarLines returns the lines of the file with code of its profile blocks, covered if any of those blocks is.
+// The blocks with code on the same line are its branches, e.g. the condition and the body of a one-line
+// if statement or a function literal. A block ending at the first column of its last line, right after
+// the newline of its last statement, has no code on that line, such as the body before "} else if".
+func sonarLines(file *GoFile) []*SonarLine {
+	numLines := 0
+	for _, block := range file.Profile {
+		numLines = max(numLines, block.EndLine)
+	}
+	branches, coveredBranches := make([]int, numLines), make([]int, numLines)
+	for _, block := range file.Profile {
+		lastLine := block.EndLine
+		if block.EndCol <= 1 && block.EndLine > block.StartLine {
+			lastLine--
+		}
+		for idx := block.StartLine - 1; idx < lastLine; idx++ {
+			branches[idx]++
+			if block.Count > 0 {
+				coveredBranches[idx]++
+			}
+		}
+	}
+
+	var lines []*SonarLine
+	for idx := range branches {
+		if branches[idx] == 0 {
+			continue
+		}
+		line := &SonarLine{LineNumber: idx + 1, Covered: coveredBranches[idx] > 0}
+		if branches[idx] > 1 {
+			line.BranchesToCover, line.CoveredBranches = branches[idx], &coveredBranches[idx]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
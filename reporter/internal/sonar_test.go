@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/cover"
+)
+
+func TestSonarRender(t *testing.T) {
+	root := t.TempDir()
+	gp := NewGoProject("example.com/a", nil)
+	gp.Root().AddFile(&GoFile{
+		GoListItem: NewGoListItem("example.com/a/a.go"),
+		ABSPath:    filepath.Join(root, "a", "a.go"),
+		Profile: []cover.ProfileBlock{
+			{StartLine: 2, StartCol: 2, EndLine: 3, EndCol: 10, NumStmt: 2, Count: 1},
+			{StartLine: 5, StartCol: 2, EndLine: 5, EndCol: 12, NumStmt: 1, Count: 1},
+			{StartLine: 5, StartCol: 12, EndLine: 5, EndCol: 20, NumStmt: 1, Count: 0},
+			{StartLine: 6, StartCol: 2, EndLine: 6, EndCol: 10, NumStmt: 1, Count: 0},
+			{StartLine: 8, StartCol: 3, EndLine: 9, EndCol: 1, NumStmt: 1, Count: 1},
+			{StartLine: 9, StartCol: 9, EndLine: 9, EndCol: 20, NumStmt: 1, Count: 0},
+			{StartLine: 11, StartCol: 3, EndLine: 12, EndCol: 1, NumStmt: 1, Count: 1},
+		},
+	})
+	gp.Root().AddFile(&GoFile{
+		GoListItem: NewGoListItem("example.com/a/b.go"),
+		ABSPath:    filepath.Join(t.TempDir(), "b.go"),
+	})
+
+	t.Run("should write the lines to cover of files in the repository", func(t *testing.T) {
+		var buf strings.Builder
+		assert.NoError(t, (&SonarRenderer{RepoRoot: root}).Render(gp, &buf))
+		assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<coverage version="1">
+  <file path="a/a.go">
+    <lineToCover lineNumber="2" covered="true"></lineToCover>
+    <lineToCover lineNumber="3" covered="true"></lineToCover>
+    <lineToCover lineNumber="5" covered="true" branchesToCover="2" coveredBranches="1"></lineToCover>
+    <lineToCover lineNumber="6" covered="false"></lineToCover>
+    <lineToCover lineNumber="8" covered="true"></lineToCover>
+    <lineToCover lineNumber="9" covered="false"></lineToCover>
+    <lineToCover lineNumber="11" covered="true"></lineToCover>
+  </file>
+</coverage>
+`, buf.String())
+	})
+
+	t.Run("should write an empty report without files in the repository", func(t *testing.T) {
+		var buf strings.Builder
+		assert.NoError(t, (&SonarRenderer{RepoRoot: filepath.Join(root, "b")}).Render(gp, &buf))
+		assert.Equal(t, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<coverage version=\"1\"></coverage>\n", buf.String())
+	})
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+const (
+	// SortName keeps the order of the tree, by name.
+	SortName = "name"
+	// SortCoverage sorts the least covered first.
+	SortCoverage = "coverage"
+)
+
+// SortModes are the names of the orders of the rows of summaries.
+var SortModes = []string{SortName, SortCoverage}
+
+const (
+	// ColorAuto colors the output when it is a terminal and NO_COLOR is not set.
+	ColorAuto = "auto"
+	// ColorAlways always colors the output.
+	ColorAlways = "always"
+	// ColorNever never colors the output.
+	ColorNever = "never"
+)
+
+// ColorModes are the names of the ways to color the text output.
+var ColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+
+// ansiColors are the ANSI escape codes of the coverage class names.
+var ansiColors = map[string]string{
+	"safe":    "\x1b[32m",
+	"warning": "\x1b[33m",
+	"danger":  "\x1b[31m",
+}
+
+const ansiReset = "\x1b[0m"
+
+// TextRenderer renders a GoProject as a summary table for terminals, similar to 'go tool cover -func' but aggregated.
+type TextRenderer struct {
+	// Depth limits the levels of the tree below the initial directory. Every level is shown when not positive.
+	Depth int
+	// Flat lists the packages with their totals instead of the tree.
+	Flat bool
+	// Sort is one of SortModes. SortName is used when empty.
+	Sort string
+	// Color is one of ColorModes. ColorAuto is used when empty.
+	Color string
+}
+
+// NewTextRenderer returns a new TextRenderer for the given configuration.
+func NewTextRenderer(cfg *config.Config) (Renderer, error) {
+	if cfg.Sort != "" && !slices.Contains(SortModes, cfg.Sort) {
+		return nil, fmt.Errorf("unknown sort %q, expected one of %s", cfg.Sort, strings.Join(SortModes, ", "))
+	}
+	if cfg.Color != "" && !slices.Contains(ColorModes, cfg.Color) {
+		return nil, fmt.Errorf("unknown color %q, expected one of %s", cfg.Color, strings.Join(ColorModes, ", "))
+	}
+	return &TextRenderer{Depth: cfg.Depth, Flat: cfg.Flat, Sort: cfg.Sort, Color: cfg.Color}, nil
+}
+
+// textRow is a row of the summary table.
+type textRow struct {
+	Name string
+	Item *GoListItem
+}
+
+// Render writes the summary table of the GoProject to the provided io.Writer.
+func (r *TextRenderer) Render(gp *GoProject, wr io.Writer) error {
+	var rows []*textRow
+	initialDir := gp.InitialDir()
+	if r.Flat {
+		rows = r.packageRows(initialDir)
+		rows = append(rows, &textRow{Name: "total", Item: initialDir.GoListItem})
+	} else {
+		rows = append(rows, &textRow{Name: rootTitle(initialDir), Item: initialDir.GoListItem})
+		rows = r.treeRows(rows, initialDir, 1)
+	}
+
+	nameWidth, stmtsWidth := len("PATH"), len("STATEMENTS")
+	for _, row := range rows {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(row.Name))
+		stmtsWidth = max(stmtsWidth, len(textStmts(row.Item)))
+	}
+
+	color := r.colored(wr) && gp.Cutlines != nil
+	bw := bufio.NewWriter(wr)
+	fmt.Fprintf(bw, "%-*s  %*s  %7s\n", nameWidth, "PATH", stmtsWidth, "STATEMENTS", "PERCENT")
+	for _, row := range rows {
+		percent := fmt.Sprintf("%6.1f%%", row.Item.Percent())
+		if color {
+			if ansiColor, ok := ansiColors[coverageClassName(row.Item, gp.Cutlines)]; ok {
+				percent = ansiColor + percent + ansiReset
+			}
+		}
+		padding := nameWidth - utf8.RuneCountInString(row.Name)
+		fmt.Fprintf(bw, "%s%s  %*s  %s\n", row.Name, strings.Repeat(" ", padding), stmtsWidth, textStmts(row.Item), percent)
+	}
+	return bw.Flush()
+}
+
+// treeRows appends the rows of the subdirectories and files of the directory at the level, indented by the level.
+func (r *TextRenderer) treeRows(rows []*textRow, dir *GoDir, level int) []*textRow {
+	type child struct {
+		item *GoListItem
+		dir  *GoDir
+	}
+	children := make([]child, 0, len(dir.SubDirs)+len(dir.Files))
+	for _, subDir := range dir.SubDirs {
+		children = append(children, child{subDir.GoListItem, subDir})
+	}
+	for _, file := range dir.Files {
+		children = append(children, child{item: file.GoListItem})
+	}
+	if r.Sort == SortCoverage {
+		sort.SliceStable(children, func(i, j int) bool { return children[i].item.Percent() < children[j].item.Percent() })
+	}
+
+	indent := strings.Repeat("  ", level)
+	for _, c := range children {
+		rows = append(rows, &textRow{Name: indent + c.item.Title, Item: c.item})
+		if c.dir != nil && (r.Depth <= 0 || level < r.Depth) {
+			rows = r.treeRows(rows, c.dir, level+1)
+		}
+	}
+	return rows
+}
+
+// packageRows returns the rows of the directories with files under the directory, named by their paths.
+// Each row counts the files of its directory only.
+func (r *TextRenderer) packageRows(dir *GoDir) []*textRow {
+	var rows []*textRow
+	for _, pkg := range dir.Packages() {
+		rows = append(rows, &textRow{Name: pkg.Path, Item: pkg.PackageItem()})
+	}
+
+	if r.Sort == SortCoverage {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Item.Percent() < rows[j].Item.Percent() })
+	} else {
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	}
+	return rows
+}
+
+// colored reports whether the output to the writer is colored.
+func (r *TextRenderer) colored(wr io.Writer) bool {
+	switch r.Color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	file, ok := wr.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// textStmts returns the covered and total statements of the item, e.g. "7/10".
+func textStmts(item *GoListItem) string {
+	return fmt.Sprintf("%d/%d", item.StmtCoveredCount, item.StmtCount)
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTextTestProject() *GoProject {
+	gp := NewGoProject("a", &config.Cutlines{Safe: 70, Warning: 40})
+	for _, f := range []struct {
+		path           string
+		stmts, covered int
+	}{
+		{"a/b/c.go", 10, 5},
+		{"a/b/d/e.go", 4, 4},
+		{"a/f.go", 10, 1},
+	} {
+		file := &GoFile{GoListItem: NewGoListItem(f.path)}
+		file.StmtCount = f.stmts
+		file.StmtCoveredCount = f.covered
+		gp.SafeDir(f.path[:strings.LastIndex(f.path, "/")]).AddFile(file)
+	}
+	gp.Root().Aggregate()
+	return gp
+}
+
+func TestTextRender(t *testing.T) {
+	gp := newTextTestProject()
+
+	t.Run("should render tree", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&TextRenderer{}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"PATH        STATEMENTS  PERCENT\n"+
+			"a                10/24    41.7%\n"+
+			"  b               9/14    64.3%\n"+
+			"    d              4/4   100.0%\n"+
+			"      e.go         4/4   100.0%\n"+
+			"    c.go          5/10    50.0%\n"+
+			"  f.go            1/10    10.0%\n", buf.String())
+	})
+
+	t.Run("should limit depth and sort by least coverage", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&TextRenderer{Depth: 2, Sort: SortCoverage}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"PATH      STATEMENTS  PERCENT\n"+
+			"a              10/24    41.7%\n"+
+			"  f.go          1/10    10.0%\n"+
+			"  b             9/14    64.3%\n"+
+			"    c.go        5/10    50.0%\n"+
+			"    d            4/4   100.0%\n", buf.String())
+	})
+
+	t.Run("should list packages", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&TextRenderer{Flat: true, Sort: SortCoverage}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, ""+
+			"PATH   STATEMENTS  PERCENT\n"+
+			"a            1/10    10.0%\n"+
+			"a/b          5/10    50.0%\n"+
+			"a/b/d         4/4   100.0%\n"+
+			"total       10/24    41.7%\n", buf.String())
+	})
+
+	t.Run("should color percents by cutlines", func(t *testing.T) {
+		var buf strings.Builder
+		err := (&TextRenderer{Color: ColorAlways}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "\x1b[33m  41.7%\x1b[0m\n")
+		assert.Contains(t, buf.String(), "\x1b[32m 100.0%\x1b[0m\n")
+		assert.Contains(t, buf.String(), "\x1b[31m  10.0%\x1b[0m\n")
+
+		buf.Reset()
+		err = (&TextRenderer{}).Render(gp, &buf)
+		assert.NoError(t, err)
+		assert.NotContains(t, buf.String(), "\x1b[")
+	})
+
+	t.Run("should not color percents without cutlines", func(t *testing.T) {
+		gp := newTextTestProject()
+		gp.Cutlines = nil
+		for _, color := range ColorModes {
+			var buf strings.Builder
+			err := (&TextRenderer{Color: color}).Render(gp, &buf)
+			assert.NoError(t, err)
+			assert.Contains(t, buf.String(), "  41.7%\n")
+			assert.NotContains(t, buf.String(), "\x1b[")
+		}
+	})
+}
+
+func TestNewTextRenderer(t *testing.T) {
+	t.Run("should return error when sort or color is unknown", func(t *testing.T) {
+		_, err := NewTextRenderer(&config.Config{Sort: "size"})
+		assert.ErrorContains(t, err, `unknown sort "size"`)
+
+		_, err = NewTextRenderer(&config.Config{Color: "rainbow"})
+		assert.ErrorContains(t, err, `unknown color "rainbow"`)
+	})
+}
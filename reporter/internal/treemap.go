@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/cancue/covreport/reporter/config"
+)
+
+const (
+	// treemapWidth and treemapHeight are the units the treemap is laid out in.
+	// Their ratio must match the aspect ratio of the treemap element.
+	treemapWidth  = 300
+	treemapHeight = 100
+	// treemapPadding is the inset of the nested cells of a directory.
+	treemapPadding = 1
+	// treemapLabelHeight is the space reserved for the label of a directory with nested cells.
+	treemapLabelHeight = 7
+	// treemapMinNestedSize is the minimum size of a directory cell to show its nested cells.
+	treemapMinNestedSize = 16
+)
+
+// TemplateTreemapCellData represents a cell of the treemap of a directory view.
+// X, Y, Width and Height are in percent of the treemap.
+type TemplateTreemapCellData struct {
+	ID        string
+	Title     string
+	ClassName string
+	Percent   string
+	Depth     int
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+}
+
+// treemapRect is a rectangle in treemap units.
+type treemapRect struct {
+	X, Y, W, H float64
+}
+
+// NewTreemap lays out the subdirectories and files of the directory as a squarified treemap sized by statements.
+// Subdirectories that are large enough contain the cells of their own items.
+func NewTreemap(dir *GoDir, cutlines *config.Cutlines) []*TemplateTreemapCellData {
+	var cells []*TemplateTreemapCellData
+	addTreemapCells(&cells, dir, treemapRect{0, 0, treemapWidth, treemapHeight}, 1, cutlines)
+	return cells
+}
+
+// addTreemapCells appends the cells of the items of the directory laid out in the area.
+func addTreemapCells(cells *[]*TemplateTreemapCellData, dir *GoDir, area treemapRect, depth int, cutlines *config.Cutlines) {
+	var items []*GoListItem
+	var subDirs []*GoDir
+	for _, subDir := range dir.SubDirs {
+		if subDir.StmtCount > 0 {
+			items = append(items, subDir.GoListItem)
+			subDirs = append(subDirs, subDir)
+		}
+	}
+	for _, file := range dir.Files {
+		if file.StmtCount > 0 {
+			items = append(items, file.GoListItem)
+		}
+	}
+
+	sizes := make([]float64, len(items))
+	for i, item := range items {
+		sizes[i] = float64(item.StmtCount)
+	}
+
+	for i, rect := range squarify(sizes, area) {
+		item := items[i]
+		*cells = append(*cells, &TemplateTreemapCellData{
+			ID:        item.ID,
+			Title:     item.Title,
+			ClassName: coverageClassName(item, cutlines),
+			Percent:   fmt.Sprintf("%.1f%%", item.Percent()),
+			Depth:     depth,
+			X:         rect.X / treemapWidth * 100,
+			Y:         rect.Y / treemapHeight * 100,
+			Width:     rect.W / treemapWidth * 100,
+			Height:    rect.H / treemapHeight * 100,
+		})
+
+		if depth == 1 && i < len(subDirs) && rect.W >= treemapMinNestedSize && rect.H >= treemapMinNestedSize {
+			inner := treemapRect{
+				X: rect.X + treemapPadding,
+				Y: rect.Y + treemapLabelHeight,
+				W: rect.W - 2*treemapPadding,
+				H: rect.H - treemapLabelHeight - treemapPadding,
+			}
+			addTreemapCells(cells, subDirs[i], inner, depth+1, cutlines)
+		}
+	}
+}
+
+// squarify lays out rectangles with the given sizes in the area, keeping their aspect ratios close to 1.
+// The returned rectangles are in the same order as the sizes.
+// See Bruls, Huizing and van Wijk, "Squarified Treemaps".
+func squarify(sizes []float64, area treemapRect) []treemapRect {
+	var total float64
+	for _, size := range sizes {
+		total += size
+	}
+	rects := make([]treemapRect, len(sizes))
+	if total <= 0 {
+		return rects
+	}
+
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+
+	scale := area.W * area.H / total
+	values := make([]float64, len(sizes))
+	for i, idx := range order {
+		values[i] = sizes[idx] * scale
+	}
+
+	x, y, w, h := area.X, area.Y, area.W, area.H
+	for start := 0; start < len(values); {
+		side := math.Min(w, h)
+		end := start + 1
+		for end < len(values) && worstRatio(values[start:end+1], side) <= worstRatio(values[start:end], side) {
+			end++
+		}
+
+		var sum float64
+		for _, value := range values[start:end] {
+			sum += value
+		}
+		if w >= h {
+			rowWidth := sum / h
+			offset := y
+			for i := start; i < end; i++ {
+				rowHeight := values[i] / rowWidth
+				rects[order[i]] = treemapRect{x, offset, rowWidth, rowHeight}
+				offset += rowHeight
+			}
+			x += rowWidth
+			w -= rowWidth
+		} else {
+			rowHeight := sum / w
+			offset := x
+			for i := start; i < end; i++ {
+				rowWidth := values[i] / rowHeight
+				rects[order[i]] = treemapRect{offset, y, rowWidth, rowHeight}
+				offset += rowWidth
+			}
+			y += rowHeight
+			h -= rowHeight
+		}
+		start = end
+	}
+	return rects
+}
+
+// worstRatio returns the worst aspect ratio of a row of values laid out along a side.
+func worstRatio(row []float64, side float64) float64 {
+	var sum float64
+	minValue, maxValue := math.Inf(1), 0.0
+	for _, value := range row {
+		sum += value
+		minValue = math.Min(minValue, value)
+		maxValue = math.Max(maxValue, value)
+	}
+	sideSquare := side * side
+	sumSquare := sum * sum
+	return math.Max(sideSquare*maxValue/sumSquare, sumSquare/(sideSquare*minValue))
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/cancue/covreport/reporter/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSquarify(t *testing.T) {
+	t.Run("should fill the area with rects proportional to sizes", func(t *testing.T) {
+		area := treemapRect{X: 10, Y: 20, W: 600, H: 400}
+		sizes := []float64{6, 6, 4, 3, 2, 2, 1}
+		rects := squarify(sizes, area)
+		assert.Len(t, rects, len(sizes))
+
+		var total float64
+		for i, rect := range rects {
+			assert.InDelta(t, sizes[i]*1e4, rect.W*rect.H, 1e-6)
+			assert.GreaterOrEqual(t, rect.X, area.X-1e-9)
+			assert.GreaterOrEqual(t, rect.Y, area.Y-1e-9)
+			assert.LessOrEqual(t, rect.X+rect.W, area.X+area.W+1e-9)
+			assert.LessOrEqual(t, rect.Y+rect.H, area.Y+area.H+1e-9)
+			total += rect.W * rect.H
+		}
+		assert.InDelta(t, area.W*area.H, total, 1e-6)
+	})
+
+	t.Run("should keep the order of sizes", func(t *testing.T) {
+		rects := squarify([]float64{1, 3}, treemapRect{W: 4, H: 1})
+		assert.InDelta(t, 1.0, rects[0].W*rects[0].H, 1e-9)
+		assert.InDelta(t, 3.0, rects[1].W*rects[1].H, 1e-9)
+	})
+
+	t.Run("should return empty rects when there is nothing to lay out", func(t *testing.T) {
+		assert.Equal(t, []treemapRect{{}}, squarify([]float64{0}, treemapRect{W: 1, H: 1}))
+	})
+}
+
+func TestNewTreemap(t *testing.T) {
+	cutlines := &config.Cutlines{Safe: 70, Warning: 40}
+	gp := NewGoProject("a", cutlines)
+	addFile := func(relPkgPath string, stmts, covered int) {
+		file := &GoFile{GoListItem: NewGoListItem(relPkgPath)}
+		file.StmtCount = stmts
+		file.StmtCoveredCount = covered
+		gp.SafeDir(relPkgPath[:len(relPkgPath)-len(file.Title)-1]).AddFile(file)
+	}
+	addFile("a/b/c.go", 60, 60)
+	addFile("a/b/d.go", 20, 0)
+	addFile("a/e.go", 20, 10)
+	addFile("a/empty.go", 0, 0)
+	gp.Root().Aggregate()
+
+	cells := NewTreemap(gp.Root(), cutlines)
+	titles := make([]string, len(cells))
+	for i, cell := range cells {
+		titles[i] = cell.Title
+	}
+	assert.Equal(t, []string{"b", "c.go", "d.go", "e.go"}, titles)
+
+	b, c, d, e := cells[0], cells[1], cells[2], cells[3]
+	assert.Equal(t, 1, b.Depth)
+	assert.Equal(t, 2, c.Depth)
+	assert.Equal(t, 1, e.Depth)
+	assert.Equal(t, "safe", b.ClassName)
+	assert.Equal(t, "safe", c.ClassName)
+	assert.Equal(t, "danger", d.ClassName)
+	assert.Equal(t, "warning", e.ClassName)
+	assert.Equal(t, "50.0%", e.Percent)
+	assert.InDelta(t, 80.0, b.Width*b.Height/100, 1e-6)
+	assert.InDelta(t, 20.0, e.Width*e.Height/100, 1e-6)
+
+	for _, nested := range []*TemplateTreemapCellData{c, d} {
+		assert.Greater(t, nested.X, b.X)
+		assert.Greater(t, nested.Y, b.Y)
+		assert.Less(t, nested.X+nested.Width, b.X+b.Width)
+		assert.Less(t, nested.Y+nested.Height, b.Y+b.Height)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -12,32 +15,162 @@ import (
 	"github.com/cancue/covreport/reporter/internal"
 )
 
-// Report generates a coverage report using the given configuration.
+// Report generates coverage reports using the given configuration.
+// The profiles are parsed once and rendered to every requested output.
 func Report(cfg *config.Config) error {
+	outputs, err := ParseOutputs(cfg.Output)
+	if err != nil {
+		return err
+	}
+
+	renderers := make([]internal.Renderer, len(outputs))
+	for i, output := range outputs {
+		renderers[i], err = internal.NewRenderer(output.Format, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
 	gp := internal.NewGoProject(cfg.Root, cfg.Cutlines)
-	if err := gp.Parse(cfg.Input); err != nil {
+	if roots := strings.Split(cfg.Root, ","); len(roots) > 1 || isWorkspaceModule(cfg.Src, cfg.Root) {
+		// Several roots are shown as the top-level directories of the whole project,
+		// and a single module of a workspace is shown like the main module.
+		gp = internal.NewGoProject(".", cfg.Cutlines)
+		gp.Includes = roots
+	}
+	gp.Resolver = &internal.Resolver{
+		Strategy:  cfg.Resolver,
+		Dir:       cfg.Src,
+		SourceMap: cfg.SourceMap,
+		Go:        cfg.Go,
+		Tags:      cfg.Tags,
+		GoFlags:   cfg.GoFlags,
+		GOOS:      cfg.GOOS,
+		GOARCH:    cfg.GOARCH,
+	}
+	gp.Tolerant = cfg.Tolerant
+	gp.Deps = cfg.Deps
+	if err := parse(gp, cfg.Input); err != nil {
 		return err
 	}
+	for _, warning := range gp.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
 
-	file, err := os.Create(cfg.Output)
-	if err != nil {
-		return fmt.Errorf("can't create %q: %v", cfg.Output, err)
+	for i, output := range outputs {
+		if err := render(gp, renderers[i], output.Path); err != nil {
+			return err
+		}
 	}
-	defer file.Close()
 
-	if err := gp.Report(file); err != nil {
+	return nil
+}
+
+// isWorkspaceModule reports whether the root is a module of the go.work workspace of the directory.
+func isWorkspaceModule(dir string, root string) bool {
+	modules, err := internal.FindModules(dir)
+	if err != nil || len(modules) < 2 {
+		return false
+	}
+	return slices.ContainsFunc(modules, func(module *internal.Module) bool { return module.Path == root })
+}
+
+// parse parses the profiles of the named file, or of the standard input if the name is "-".
+func parse(gp *internal.GoProject, input string) error {
+	if input == "-" {
+		return gp.ParseReader(os.Stdin)
+	}
+	return gp.Parse(input)
+}
+
+// render renders the GoProject with the renderer to the named file, or to the standard output if the name is "-",
+// or to the named directory if the renderer is a DirRenderer.
+func render(gp *internal.GoProject, renderer internal.Renderer, path string) error {
+	if path == "-" {
+		return renderer.Render(gp, os.Stdout)
+	}
+	if dirRenderer, ok := renderer.(internal.DirRenderer); ok {
+		return dirRenderer.RenderDir(gp, path)
+	}
+
+	// A regular file is replaced by a temporary file only once the report is complete, since views are streamed and
+	// a source can fail to be read after part of the report is written. Other files such as symlinks, devices and
+	// named pipes are written to directly.
+	info, err := os.Lstat(path)
+	if err == nil && !info.Mode().IsRegular() {
+		return renderFile(gp, renderer, path, os.O_WRONLY|os.O_TRUNC, 0o666)
+	}
+	perm := os.FileMode(0o666)
+	if err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.%d.tmp", filepath.Base(path), os.Getpid()))
+	defer os.Remove(tmpPath)
+	if err := renderFile(gp, renderer, tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm); err != nil {
 		return err
 	}
+	if info != nil {
+		// The mode of the existing file is kept regardless of the umask.
+		if err := os.Chmod(tmpPath, perm); err != nil {
+			return fmt.Errorf("can't write %q: %v", path, err)
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("can't write %q: %v", path, err)
+	}
+	return nil
+}
 
+// renderFile renders the GoProject with the renderer to the named file opened with the flag and permission.
+func renderFile(gp *internal.GoProject, renderer internal.Renderer, path string, flag int, perm os.FileMode) error {
+	file, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return fmt.Errorf("can't create %q: %v", path, err)
+	}
+	if err := renderer.Render(gp, file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("can't write %q: %v", path, err)
+	}
 	return nil
 }
 
 // NewCLIConfig creates a new configuration based on the command-line arguments.
 func NewCLIConfig() (*config.Config, error) {
-	input := flag.String("i", "cover.prof", "input file name")
-	output := flag.String("o", "cover.html", "output file name")
+	input := flag.String("i", "cover.prof", "input file name, or - for stdin")
+	output := flag.String("o", "cover.html", fmt.Sprintf("output file names, comma-separated [format:]path, - for stdout (formats: %s)", strings.Join(internal.Formats(), ", ")))
 	cutlines := flag.String("cutlines", "70,40", "cutlines (safe,warning)")
-	root := flag.String("root", ".", "root package name")
+	root := flag.String("root", ".", "root package name, or comma-separated module or package names to include")
+	tmpl := flag.String("template", "", "custom html template file name")
+	css := flag.String("css", "", "custom css file name")
+	meta := flag.String("meta", "", "metadata shown in the html header (key=value,...)")
+	palette := flag.String("palette", "default", fmt.Sprintf("html color palette (%s)", strings.Join(internal.Palettes, ", ")))
+	sidebar := flag.Bool("sidebar", false, "show tree sidebar navigation in html")
+	jobs := flag.Int("j", runtime.NumCPU(), "number of source files loaded concurrently")
+	lazy := flag.Bool("lazy", false, "embed compressed sources in html, rendered when opened")
+	resolver := flag.String("resolver", internal.ResolveAuto, fmt.Sprintf("package resolver (%s)", strings.Join(internal.ResolveStrategies, ", ")))
+	src := flag.String("src", "", "source root packages are resolved from")
+	sourceMap := flag.String("source-map", "", "source path prefix rewrite rules (old=new,...)")
+	tolerant := flag.Bool("tolerant", false, "render files with missing sources from the profile only, instead of failing")
+	deps := flag.String("deps", internal.DepsInclude, fmt.Sprintf("replaced and vendored modules (%s)", strings.Join(internal.DepsModes, ", ")))
+	goTool := flag.String("go", "", "go command used to resolve packages (default go in PATH)")
+	tags := flag.String("tags", "", "comma-separated build tags used to resolve packages")
+	goflags := flag.String("goflags", "", "GOFLAGS used to resolve packages, e.g. -mod=vendor")
+	goos := flag.String("goos", "", "GOOS used to resolve packages")
+	goarch := flag.String("goarch", "", "GOARCH used to resolve packages")
+	depth := flag.Int("depth", 0, "levels of the text summary tree, 0 for all")
+	flat := flag.Bool("flat", false, "list packages instead of the tree in the text summary")
+	sortMode := flag.String("sort", internal.SortName, fmt.Sprintf("order of summary rows (%s)", strings.Join(internal.SortModes, ", ")))
+	color := flag.String("color", internal.ColorAuto, fmt.Sprintf("color of the text summary (%s)", strings.Join(internal.ColorModes, ", ")))
+	baseline := flag.String("baseline", "", "previous json report the markdown summary shows deltas against")
+	worst := flag.Int("worst", 10, "number of the least covered files listed in the markdown summary")
+	badgeDir := flag.String("badge-dir", "", "directory whose coverage badges show (default the initial directory)")
+	repoRoot := flag.String("repo-root", "", "repository root annotated paths are relative to (default the one containing -src)")
+	changed := flag.String("changed", "", "unified diff limiting annotations to changed lines, e.g. from git diff")
+	sarifResults := flag.String("sarif-results", internal.SARIFBlocks, fmt.Sprintf("uncovered code reported by sarif (%s)", strings.Join(internal.SARIFResultModes, ", ")))
 	flag.Parse()
 
 	parsedCutlines, err := ParseCutlines(*cutlines)
@@ -45,11 +178,48 @@ func NewCLIConfig() (*config.Config, error) {
 		return nil, err
 	}
 
+	parsedMeta, err := ParseMeta(*meta)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedSourceMap, err := ParseSourceMap(*sourceMap)
+	if err != nil {
+		return nil, err
+	}
+
 	return &config.Config{
-		Input:    *input,
-		Output:   *output,
-		Cutlines: parsedCutlines,
-		Root:     *root,
+		Input:        *input,
+		Output:       *output,
+		Cutlines:     parsedCutlines,
+		Root:         *root,
+		Template:     *tmpl,
+		CSS:          *css,
+		Meta:         parsedMeta,
+		Palette:      *palette,
+		Sidebar:      *sidebar,
+		Jobs:         *jobs,
+		Lazy:         *lazy,
+		Resolver:     *resolver,
+		Src:          *src,
+		SourceMap:    parsedSourceMap,
+		Tolerant:     *tolerant,
+		Deps:         *deps,
+		Go:           *goTool,
+		Tags:         *tags,
+		GoFlags:      *goflags,
+		GOOS:         *goos,
+		GOARCH:       *goarch,
+		Depth:        *depth,
+		Flat:         *flat,
+		Sort:         *sortMode,
+		Color:        *color,
+		Baseline:     *baseline,
+		Worst:        *worst,
+		BadgeDir:     *badgeDir,
+		RepoRoot:     *repoRoot,
+		Changed:      *changed,
+		SARIFResults: *sarifResults,
 	}, nil
 }
 
@@ -70,3 +240,57 @@ func ParseCutlines(cutlines string) (*config.Cutlines, error) {
 		Warning: warning,
 	}, nil
 }
+
+// ParseOutputs parses the output argument.
+// Each comma-separated output is either a path or a format and a path joined by a colon.
+// The format defaults to html when omitted. A path of "-" is the standard output.
+func ParseOutputs(output string) ([]*config.Output, error) {
+	var outputs []*config.Output
+	for _, frag := range strings.Split(output, ",") {
+		format, path, ok := strings.Cut(frag, ":")
+		if !ok || len(format) < 2 {
+			// A single letter prefix is a windows drive, not a format.
+			format, path = "html", frag
+		}
+		if path == "" {
+			return nil, fmt.Errorf("empty output path in %q", output)
+		}
+		if path == "-" && slices.ContainsFunc(outputs, func(o *config.Output) bool { return o.Path == "-" }) {
+			return nil, fmt.Errorf("more than one output to stdout in %q", output)
+		}
+		outputs = append(outputs, &config.Output{Format: format, Path: path})
+	}
+	return outputs, nil
+}
+
+// ParseMeta parses the meta argument of comma-separated key=value pairs.
+func ParseMeta(meta string) (map[string]string, error) {
+	if meta == "" {
+		return nil, nil
+	}
+	parsed := make(map[string]string)
+	for _, frag := range strings.Split(meta, ",") {
+		key, value, ok := strings.Cut(frag, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid meta %q, expected key=value", frag)
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
+
+// ParseSourceMap parses the source map argument of comma-separated old=new path prefix pairs.
+func ParseSourceMap(sourceMap string) ([]*config.SourceMapping, error) {
+	if sourceMap == "" {
+		return nil, nil
+	}
+	var parsed []*config.SourceMapping
+	for _, frag := range strings.Split(sourceMap, ",") {
+		from, to, ok := strings.Cut(frag, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid source map %q, expected old=new", frag)
+		}
+		parsed = append(parsed, &config.SourceMapping{Old: from, New: to})
+	}
+	return parsed, nil
+}
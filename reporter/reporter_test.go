@@ -1,9 +1,13 @@
 package reporter_test
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cancue/covreport/reporter"
+	"github.com/cancue/covreport/reporter/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,5 +64,265 @@ func TestNewCLIConfig(t *testing.T) {
 		assert.Equal(t, 70.0, cfg.Cutlines.Safe)
 		assert.Equal(t, 40.0, cfg.Cutlines.Warning)
 		assert.Equal(t, ".", cfg.Root)
+		assert.Equal(t, "default", cfg.Palette)
+	})
+}
+
+func TestParseOutputs(t *testing.T) {
+	t.Run("should default to html format", func(t *testing.T) {
+		outputs, err := reporter.ParseOutputs("cover.html")
+		assert.NoError(t, err)
+		assert.Equal(t, []*config.Output{{Format: "html", Path: "cover.html"}}, outputs)
+
+		outputs, err = reporter.ParseOutputs(`C:\cover.html`)
+		assert.NoError(t, err)
+		assert.Equal(t, []*config.Output{{Format: "html", Path: `C:\cover.html`}}, outputs)
+	})
+
+	t.Run("should parse several outputs with formats", func(t *testing.T) {
+		outputs, err := reporter.ParseOutputs("html:cover.html,json:cover.json")
+		assert.NoError(t, err)
+		assert.Equal(t, []*config.Output{
+			{Format: "html", Path: "cover.html"},
+			{Format: "json", Path: "cover.json"},
+		}, outputs)
+	})
+
+	t.Run("should parse stdout", func(t *testing.T) {
+		outputs, err := reporter.ParseOutputs("-,json:cover.json")
+		assert.NoError(t, err)
+		assert.Equal(t, []*config.Output{
+			{Format: "html", Path: "-"},
+			{Format: "json", Path: "cover.json"},
+		}, outputs)
+
+		_, err = reporter.ParseOutputs("html:-,json:-")
+		assert.ErrorContains(t, err, "more than one output to stdout")
+	})
+
+	t.Run("should return error when path is empty", func(t *testing.T) {
+		_, err := reporter.ParseOutputs("")
+		assert.ErrorContains(t, err, "empty output path")
+
+		_, err = reporter.ParseOutputs("cover.html,json:")
+		assert.ErrorContains(t, err, "empty output path")
+	})
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "cover.prof")
+	err := os.WriteFile(input, []byte("mode: set\ngithub.com/cancue/covreport/reporter/reporter.go:1.1,2.1 2 1\n"), 0o644)
+	assert.NoError(t, err)
+
+	t.Run("should write every output from a single parse", func(t *testing.T) {
+		htmlPath := filepath.Join(dir, "cover.html")
+		jsonPath := filepath.Join(dir, "cover.json")
+		err := reporter.Report(&config.Config{
+			Input:    input,
+			Output:   fmt.Sprintf("html:%s,json:%s", htmlPath, jsonPath),
+			Root:     ".",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.NoError(t, err)
+
+		html, err := os.ReadFile(htmlPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(html), "<!DOCTYPE html>")
+
+		json, err := os.ReadFile(jsonPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(json), `"path": "reporter/reporter.go"`)
+	})
+
+	t.Run("should include every root of comma-separated roots", func(t *testing.T) {
+		jsonPath := filepath.Join(dir, "roots.json")
+		err := reporter.Report(&config.Config{
+			Input:    input,
+			Output:   "json:" + jsonPath,
+			Root:     "github.com/cancue/covreport/reporter,example.com/other",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.NoError(t, err)
+
+		json, err := os.ReadFile(jsonPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(json), `"path": "reporter/reporter.go"`)
+	})
+
+	t.Run("should show paths relative to a single root module of a workspace", func(t *testing.T) {
+		src := t.TempDir()
+		for name, content := range map[string]string{
+			"go.work":    "go 1.21\n\nuse (\n\t./m1\n\t./m2\n)\n",
+			"m1/go.mod":  "module example.com/m1\n",
+			"m1/p/p.go":  "package p\n",
+			"m2/go.mod":  "module example.com/m2\n",
+			"m2/q/q.go":  "package q\n",
+			"cover.prof": "mode: set\nexample.com/m1/p/p.go:1.1,2.1 2 1\nexample.com/m2/q/q.go:1.1,2.1 1 0\n",
+		} {
+			assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0o755))
+			assert.NoError(t, os.WriteFile(filepath.Join(src, name), []byte(content), 0o644))
+		}
+
+		jsonPath := filepath.Join(dir, "module.json")
+		err := reporter.Report(&config.Config{
+			Input:    filepath.Join(src, "cover.prof"),
+			Output:   "json:" + jsonPath,
+			Root:     "example.com/m1",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+			Resolver: "mod",
+			Src:      src,
+		})
+		assert.NoError(t, err)
+
+		json, err := os.ReadFile(jsonPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(json), `"path": "p/p.go"`)
+		assert.NotContains(t, string(json), "example.com/m2")
+	})
+
+	t.Run("should read stdin and write stdout", func(t *testing.T) {
+		stdin, stdout := os.Stdin, os.Stdout
+		defer func() { os.Stdin, os.Stdout = stdin, stdout }()
+
+		var err error
+		os.Stdin, err = os.Open(input)
+		assert.NoError(t, err)
+		defer os.Stdin.Close()
+		os.Stdout, err = os.Create(filepath.Join(dir, "stdout.json"))
+		assert.NoError(t, err)
+		defer os.Stdout.Close()
+
+		err = reporter.Report(&config.Config{
+			Input:    "-",
+			Output:   "json:-",
+			Root:     ".",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.NoError(t, err)
+
+		json, err := os.ReadFile(os.Stdout.Name())
+		assert.NoError(t, err)
+		assert.Contains(t, string(json), `"path": "reporter/reporter.go"`)
+	})
+
+	t.Run("should not leave incomplete report when source can't be read", func(t *testing.T) {
+		outDir := t.TempDir()
+		missing := filepath.Join(outDir, "missing.prof")
+		err := os.WriteFile(missing, []byte("mode: set\n./zz/b.go:1.1,2.1 1 1\n"), 0o644)
+		assert.NoError(t, err)
+		htmlPath := filepath.Join(outDir, "cover.html")
+		err = os.WriteFile(htmlPath, []byte("previous"), 0o644)
+		assert.NoError(t, err)
+
+		err = reporter.Report(&config.Config{
+			Input:    missing,
+			Output:   "html:" + htmlPath,
+			Root:     ".",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.ErrorContains(t, err, `can't read "./zz/b.go"`)
+
+		html, err := os.ReadFile(htmlPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "previous", string(html))
+		entries, err := os.ReadDir(outDir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("should write through symlink", func(t *testing.T) {
+		outDir := t.TempDir()
+		target := filepath.Join(outDir, "target.json")
+		err := os.WriteFile(target, nil, 0o644)
+		assert.NoError(t, err)
+		link := filepath.Join(outDir, "link.json")
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("can't create symlink: %v", err)
+		}
+
+		err = reporter.Report(&config.Config{
+			Input:    input,
+			Output:   "json:" + link,
+			Root:     ".",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.NoError(t, err)
+
+		info, err := os.Lstat(link)
+		assert.NoError(t, err)
+		assert.Equal(t, os.ModeSymlink, info.Mode().Type())
+		json, err := os.ReadFile(target)
+		assert.NoError(t, err)
+		assert.Contains(t, string(json), `"path": "reporter/reporter.go"`)
+	})
+
+	t.Run("should keep mode of existing file", func(t *testing.T) {
+		jsonPath := filepath.Join(t.TempDir(), "cover.json")
+		err := os.WriteFile(jsonPath, nil, 0o600)
+		assert.NoError(t, err)
+
+		err = reporter.Report(&config.Config{
+			Input:    input,
+			Output:   "json:" + jsonPath,
+			Root:     ".",
+			Cutlines: &config.Cutlines{Safe: 70, Warning: 40},
+		})
+		assert.NoError(t, err)
+
+		info, err := os.Stat(jsonPath)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("should return error when format is unknown", func(t *testing.T) {
+		err := reporter.Report(&config.Config{
+			Input:  input,
+			Output: "xml:" + filepath.Join(dir, "cover.xml"),
+		})
+		assert.ErrorContains(t, err, `unknown output format "xml"`)
+	})
+}
+
+func TestParseMeta(t *testing.T) {
+	t.Run("should parse key value pairs", func(t *testing.T) {
+		meta, err := reporter.ParseMeta("commit=abc123,branch=main,empty=")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"commit": "abc123", "branch": "main", "empty": ""}, meta)
+
+		meta, err = reporter.ParseMeta("")
+		assert.NoError(t, err)
+		assert.Nil(t, meta)
+	})
+
+	t.Run("should return error when pair is invalid", func(t *testing.T) {
+		_, err := reporter.ParseMeta("commit")
+		assert.ErrorContains(t, err, `invalid meta "commit"`)
+
+		_, err = reporter.ParseMeta("=abc")
+		assert.ErrorContains(t, err, `invalid meta "=abc"`)
+	})
+}
+
+func TestParseSourceMap(t *testing.T) {
+	t.Run("should parse old new pairs", func(t *testing.T) {
+		sourceMap, err := reporter.ParseSourceMap("/workspace=/home/me/repo,example.com/foo=.")
+		assert.NoError(t, err)
+		assert.Equal(t, []*config.SourceMapping{
+			{Old: "/workspace", New: "/home/me/repo"},
+			{Old: "example.com/foo", New: "."},
+		}, sourceMap)
+
+		sourceMap, err = reporter.ParseSourceMap("")
+		assert.NoError(t, err)
+		assert.Nil(t, sourceMap)
+	})
+
+	t.Run("should return error when pair is invalid", func(t *testing.T) {
+		_, err := reporter.ParseSourceMap("/workspace")
+		assert.ErrorContains(t, err, `invalid source map "/workspace"`)
+
+		_, err = reporter.ParseSourceMap("=/home")
+		assert.ErrorContains(t, err, `invalid source map "=/home"`)
 	})
 }